@@ -0,0 +1,408 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kind is the kind of an SQL statement
+type Kind int
+
+// The kinds of statements told apart by Classify
+const (
+	KindUnknown Kind = iota
+	KindSelect
+	KindInsert
+	KindUpdate
+	KindDelete
+	KindDDL
+	KindTransaction
+	KindPragma
+	KindAttach
+	KindVacuum
+)
+
+var kindNames = [...]string{"UNKNOWN", "SELECT", "INSERT", "UPDATE", "DELETE", "DDL", "TRANSACTION", "PRAGMA", "ATTACH", "VACUUM"}
+
+// String is a function that returns the name of the kind
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return kindNames[KindUnknown]
+	}
+	return kindNames[k]
+}
+
+// Statement is a struct holding the kind of a statement and the tables it reads and writes. Statements that
+// could not be classified are of KindUnknown and never read-only.
+type Statement struct {
+	Kind   Kind
+	Reads  []string
+	Writes []string
+	// Explain is set for EXPLAIN and EXPLAIN QUERY PLAN statements, which only describe the statement
+	Explain  bool
+	readOnly bool
+}
+
+// ReadOnly is a function that reports whether the statement never modifies the database
+func (s Statement) ReadOnly() bool {
+	return s.readOnly || s.Explain
+}
+
+// Statement is a function that classifies the query without resetting it
+func (q *Query) Statement() Statement {
+	return Classify(string(q.query))
+}
+
+// Classify is a function that returns the kind of an SQL string and the tables it reads and writes.
+// A string holding several statements of different kinds is of KindUnknown.
+func Classify(sql string) Statement {
+	var words []token
+	for _, tok := range tokenize(sql) {
+		if tok.kind != tokenSpace && tok.kind != tokenComment {
+			words = append(words, tok)
+		}
+	}
+
+	var s Statement
+	first := true
+	for len(words) > 0 {
+		n := statementLength(words)
+		if n > 0 {
+			stmt := classify(words[:n])
+			if first {
+				s = stmt
+				first = false
+			} else {
+				if stmt.Kind != s.Kind || stmt.Explain != s.Explain {
+					s.Kind, s.Explain = KindUnknown, false
+				}
+				s.readOnly = s.readOnly && stmt.readOnly
+				s.Reads = appendTables(s.Reads, stmt.Reads...)
+				s.Writes = appendTables(s.Writes, stmt.Writes...)
+			}
+		}
+		if n < len(words) {
+			n++
+		}
+		words = words[n:]
+	}
+	if s.Kind == KindUnknown {
+		s.readOnly, s.Explain = false, false
+	}
+	return s
+}
+
+// statementLength returns the number of tokens of the first statement, up to its semicolon
+func statementLength(words []token) int {
+	depth, cases, body := 0, 0, false
+	for i, tok := range words {
+		switch upper := strings.ToUpper(tok.text); {
+		case tok.text == "(":
+			depth++
+		case tok.text == ")":
+			depth--
+		case tok.kind != tokenWord:
+			if tok.text == ";" && depth == 0 && !body {
+				return i
+			}
+		case upper == "BEGIN" && i > 0 && strings.EqualFold(words[0].text, "CREATE"):
+			body = true
+		case upper == "CASE":
+			cases++
+		case upper == "END" && cases > 0:
+			cases--
+		case upper == "END" && body:
+			body = false
+		}
+	}
+	return len(words)
+}
+
+// classify classifies a single statement
+func classify(words []token) Statement {
+	var s Statement
+	i := 0
+	if word(words, i) == "EXPLAIN" {
+		s.Explain = true
+		i++
+		if word(words, i) == "QUERY" && word(words, i+1) == "PLAN" {
+			i += 2
+		}
+	}
+
+	// common table expressions are read by the statement, the tables they read are read too
+	var ctes []string
+	if word(words, i) == "WITH" {
+		i++
+		if word(words, i) == "RECURSIVE" {
+			i++
+		}
+		for i < len(words) {
+			ctes = append(ctes, tableName(words, i))
+			for i < len(words) && words[i].text != "(" {
+				i++
+			}
+			end := closing(words, i)
+			s.Reads = appendTables(s.Reads, tableReads(words[i:end])...)
+			i = end + 1
+			if i >= len(words) || words[i].text != "," {
+				break
+			}
+			i++
+		}
+	}
+
+	switch word(words, i) {
+	case "SELECT", "VALUES":
+		s.Kind, s.readOnly = KindSelect, true
+		s.Reads = appendTables(s.Reads, tableReads(words[i:])...)
+	case "INSERT", "REPLACE":
+		s.Kind = KindInsert
+		for i < len(words) && word(words, i) != "INTO" {
+			i++
+		}
+		s.Writes = appendTables(s.Writes, tableName(words, i+1))
+		s.Reads = appendTables(s.Reads, tableReads(words[i:])...)
+	case "UPDATE":
+		s.Kind = KindUpdate
+		i++
+		if word(words, i) == "OR" {
+			i += 2
+		}
+		s.Writes = appendTables(s.Writes, tableName(words, i))
+		s.Reads = appendTables(s.Reads, tableReads(words[i:])...)
+	case "DELETE":
+		s.Kind = KindDelete
+		s.Writes = appendTables(s.Writes, tableName(words, i+2))
+		s.Reads = appendTables(s.Reads, tableReads(words[i+2:])...)
+	case "CREATE", "DROP", "ALTER":
+		s.Kind = KindDDL
+		j := i + 1
+		for ; j < len(words); j++ {
+			if w := word(words, j); w == "TABLE" || w == "INDEX" || w == "VIEW" || w == "TRIGGER" {
+				break
+			}
+		}
+		j++
+		for _, w := range []string{"IF", "NOT", "EXISTS"} {
+			if word(words, j) == w {
+				j++
+			}
+		}
+		s.Writes = appendTables(s.Writes, tableName(words, j))
+		// only CREATE TABLE ... AS SELECT runs its select when executed
+		if word(words, i) == "CREATE" && word(words, i+1) != "VIEW" && word(words, i+1) != "TRIGGER" {
+			for k := j; k < len(words); k++ {
+				if word(words, k) == "AS" {
+					s.Reads = appendTables(s.Reads, tableReads(words[k:])...)
+					break
+				}
+			}
+		}
+	case "ANALYZE", "REINDEX":
+		s.Kind = KindDDL
+	case "BEGIN", "COMMIT", "END", "ROLLBACK", "SAVEPOINT", "RELEASE":
+		s.Kind = KindTransaction
+	case "PRAGMA":
+		s.Kind = KindPragma
+		var name string
+		argument := false
+		for j := i + 1; j < len(words); j++ {
+			if words[j].kind == tokenWord && (j+1 >= len(words) || words[j+1].text != ".") {
+				name = strings.ToLower(words[j].text)
+				argument = j+1 < len(words) && (words[j+1].text == "=" || words[j+1].text == "(")
+				break
+			}
+		}
+		// a pragma with an argument sets a value, except for the query pragmas taking a table, index or schema
+		switch {
+		case argument:
+			switch name {
+			case "table_info", "table_xinfo", "index_list", "index_info", "index_xinfo",
+				"foreign_key_list", "foreign_key_check", "integrity_check", "quick_check":
+				s.readOnly = true
+			}
+		case name == "optimize", name == "wal_checkpoint", name == "incremental_vacuum", name == "shrink_memory":
+		default:
+			s.readOnly = true
+		}
+	case "ATTACH", "DETACH":
+		s.Kind = KindAttach
+	case "VACUUM":
+		s.Kind = KindVacuum
+	}
+
+	var reads []string
+	for _, table := range s.Reads {
+		if !containsFold(ctes, table) {
+			reads = append(reads, table)
+		}
+	}
+	s.Reads = reads
+	return s
+}
+
+// tableReads returns the tables following FROM and JOIN keywords
+func tableReads(words []token) []string {
+	var tables []string
+	for i := 0; i < len(words); i++ {
+		switch word(words, i) {
+		case "FROM", "JOIN":
+			if w := word(words, i-1); w == "DISTINCT" {
+				continue
+			}
+			for j := i + 1; j < len(words); {
+				if words[j].text == "(" {
+					j = closing(words, j) + 1
+				} else if name := tableName(words, j); name != "" {
+					// table-valued functions are not tables
+					end := j + 1
+					if end+1 < len(words) && words[end].text == "." {
+						end += 2
+					}
+					if end >= len(words) || words[end].text != "(" {
+						tables = appendTables(tables, name)
+					}
+					j = end
+				} else {
+					break
+				}
+				// skip the alias and join constraint up to the next table of a comma-separated list
+				for j < len(words) && words[j].text != "," && !isClauseWord(word(words, j)) && words[j].text != ")" {
+					if words[j].text == "(" {
+						j = closing(words, j)
+					}
+					j++
+				}
+				if j >= len(words) || words[j].text != "," {
+					break
+				}
+				j++
+			}
+		}
+	}
+	return tables
+}
+
+// isClauseWord reports whether a word ends the table list of a FROM clause
+func isClauseWord(word string) bool {
+	switch word {
+	case "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "WINDOW", "UNION", "INTERSECT", "EXCEPT", "RETURNING",
+		"JOIN", "LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL", "ON", "USING", "SET", "SELECT", "VALUES":
+		return true
+	}
+	return false
+}
+
+// tableName returns the possibly schema-qualified table name at i, unquoted, or an empty string
+func tableName(words []token, i int) string {
+	if i >= len(words) || words[i].kind != tokenWord && words[i].kind != tokenIdent && words[i].kind != tokenString {
+		return ""
+	}
+	qualified := i+2 < len(words) && words[i+1].text == "."
+	if words[i].kind == tokenWord && keywords[strings.ToUpper(words[i].text)] && !qualified {
+		return ""
+	}
+	name := unquote(words[i].text)
+	if qualified {
+		name += "." + unquote(words[i+2].text)
+	}
+	return name
+}
+
+// unquote removes the quotes of an identifier
+func unquote(name string) string {
+	if len(name) < 2 {
+		return name
+	}
+	switch name[0] {
+	case '"', '`', '\'':
+		q := string(name[0])
+		return strings.ReplaceAll(name[1:len(name)-1], q+q, q)
+	case '[':
+		return name[1 : len(name)-1]
+	}
+	return name
+}
+
+// closing returns the index of the parenthesis closing the one at i
+func closing(words []token, i int) int {
+	depth := 0
+	for j := i; j < len(words); j++ {
+		switch words[j].text {
+		case "(":
+			depth++
+		case ")":
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(words) - 1
+}
+
+// word returns the upper-cased word at i, or an empty string when it is not a word
+func word(words []token, i int) string {
+	if i < 0 || i >= len(words) || words[i].kind != tokenWord {
+		return ""
+	}
+	return strings.ToUpper(words[i].text)
+}
+
+// appendTables appends the tables not already in the list
+func appendTables(list []string, tables ...string) []string {
+	for _, table := range tables {
+		if table != "" && !containsFold(list, table) {
+			list = append(list, table)
+		}
+	}
+	return list
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrReadOnly is the error returned by a read-only executor for a statement that may modify the database
+var ErrReadOnly = errors.New("query: statement is not read-only")
+
+// ReadOnlyExecutor is a struct implementing Executor that refuses the statements that may modify the database
+type ReadOnlyExecutor struct {
+	db Executor
+}
+
+// ReadOnly is a function that wraps an executor so that only read-only statements run on it
+func ReadOnly(db Executor) *ReadOnlyExecutor {
+	return &ReadOnlyExecutor{db: db}
+}
+
+// ExecContext is a function that executes a query string on the executor when it is read-only
+func (r *ReadOnlyExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := checkReadOnly(query); err != nil {
+		return nil, err
+	}
+	return r.db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext is a function that runs a query string on the executor when it is read-only
+func (r *ReadOnlyExecutor) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := checkReadOnly(query); err != nil {
+		return nil, err
+	}
+	return r.db.QueryContext(ctx, query, args...)
+}
+
+func checkReadOnly(query string) error {
+	if s := Classify(query); !s.ReadOnly() {
+		return fmt.Errorf("%w: %s %q", ErrReadOnly, s.Kind, query)
+	}
+	return nil
+}
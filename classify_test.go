@@ -0,0 +1,92 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		query    string
+		kind     query.Kind
+		reads    []string
+		writes   []string
+		readOnly bool
+	}{
+		{
+			query.Select("u.id", "t.name").From("users u").LeftJoin("teams t", "t.id = u.team_id").
+				Where("u.id IN (SELECT user_id FROM main.admins)").String(),
+			query.KindSelect, []string{"users", "teams", "main.admins"}, nil, true,
+		},
+		{"SELECT * FROM a, \"b c\" AS b, json_each(a.data) WHERE a.id = b.id", query.KindSelect, []string{"a", "b c"}, nil, true},
+		{"WITH recent AS (SELECT * FROM posts) SELECT * FROM recent JOIN users USING (id)", query.KindSelect, []string{"posts", "users"}, nil, true},
+		{query.InsertInto("log").Columns("msg").Values("hi").String(), query.KindInsert, nil, []string{"log"}, false},
+		{"INSERT OR REPLACE INTO archive SELECT * FROM posts WHERE old", query.KindInsert, []string{"posts"}, []string{"archive"}, false},
+		{query.Update("users", "").Set([]*query.Field{{Name: "name", Value: "x"}}).Where("id = ?").String(), query.KindUpdate, nil, []string{"users"}, false},
+		{"UPDATE OR IGNORE temp.counts SET n = n + 1 FROM totals WHERE counts.id = totals.id", query.KindUpdate, []string{"totals"}, []string{"temp.counts"}, false},
+		{query.DeleteFrom("users").Where("id IN (SELECT id FROM banned)").String(), query.KindDelete, []string{"banned"}, []string{"users"}, false},
+		{query.CreateTable("foo", []query.Column{{Name: "id", Type: "INTEGER"}}).String(), query.KindDDL, nil, []string{"foo"}, false},
+		{"CREATE TABLE IF NOT EXISTS copy AS SELECT * FROM src", query.KindDDL, []string{"src"}, []string{"copy"}, false},
+		{"CREATE VIEW v AS SELECT * FROM src", query.KindDDL, nil, []string{"v"}, false},
+		{"CREATE TRIGGER t AFTER INSERT ON a BEGIN DELETE FROM b; END", query.KindDDL, nil, []string{"t"}, false},
+		{query.DropIndex("foo_idx").String(), query.KindDDL, nil, []string{"foo_idx"}, false},
+		{query.Begin("IMMEDIATE").String(), query.KindTransaction, nil, nil, false},
+		{query.Rollback("sp").String(), query.KindTransaction, nil, nil, false},
+		{"PRAGMA main.table_info('users')", query.KindPragma, nil, nil, true},
+		{query.PragmaJournalMode("", query.JournalModeWAL).String(), query.KindPragma, nil, nil, false},
+		{query.PragmaOptimize("", 0).String(), query.KindPragma, nil, nil, false},
+		{"PRAGMA main.journal_mode", query.KindPragma, nil, nil, true},
+		{"PRAGMA user_version(5)", query.KindPragma, nil, nil, false},
+		{"PRAGMA foreign_keys(OFF)", query.KindPragma, nil, nil, false},
+		{"PRAGMA main.journal_mode(DELETE)", query.KindPragma, nil, nil, false},
+		{query.PragmaWALCheckpoint("", query.CheckpointTruncate).String(), query.KindPragma, nil, nil, false},
+		{query.PragmaIntegrityCheck("main", 10).String(), query.KindPragma, nil, nil, true},
+		{query.AttachDatabase("a.db", "a").String(), query.KindAttach, nil, nil, false},
+		{query.Vacuum("", "").String(), query.KindVacuum, nil, nil, false},
+		{"EXPLAIN QUERY PLAN DELETE FROM users", query.KindDelete, nil, []string{"users"}, true},
+		{"SELECT 1; DELETE FROM users", query.KindUnknown, nil, []string{"users"}, false},
+		{"SELECT 1; SELECT * FROM users;", query.KindSelect, []string{"users"}, nil, true},
+		{"frobnicate", query.KindUnknown, nil, nil, false},
+	}
+	for _, test := range tests {
+		s := query.Classify(test.query)
+		if s.Kind != test.kind || !reflect.DeepEqual(s.Reads, test.reads) || !reflect.DeepEqual(s.Writes, test.writes) || s.ReadOnly() != test.readOnly {
+			t.Errorf("Expected %s reading %q and writing %q (read-only %v) for '%s', but got %s reading %q and writing %q (read-only %v)",
+				test.kind, test.reads, test.writes, test.readOnly, test.query, s.Kind, s.Reads, s.Writes, s.ReadOnly())
+		}
+	}
+}
+
+func TestQueryStatement(t *testing.T) {
+	q := query.Select("*").From("users")
+	if s := q.Statement(); s.Kind != query.KindSelect || s.Kind.String() != "SELECT" {
+		t.Errorf("Expected a SELECT statement, but got %s", s.Kind)
+	}
+	if s := q.String(); s != "SELECT * FROM users" {
+		t.Errorf("Expected the query to be kept, but got '%s'", s)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("SELECT * FROM users")
+	ro := query.ReadOnly(db)
+	ctx := context.Background()
+
+	rows, err := query.QueryRows(ctx, ro, query.Select("*").From("users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := query.Exec(ctx, ro, query.DeleteFrom("users")); !errors.Is(err, query.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, but got %v", err)
+	}
+	if statements := mock.Queries(); !reflect.DeepEqual(statements, []string{"SELECT * FROM users"}) {
+		t.Errorf("Expected only the SELECT to run, but got %q", statements)
+	}
+}
@@ -0,0 +1,13 @@
+// Command querylint reports misuses of the github.com/tinytoolkit/query builder. It runs on its own or
+// with go vet -vettool=$(which querylint).
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/tinytoolkit/query/querylint"
+)
+
+func main() {
+	singlechecker.Main(querylint.Analyzer)
+}
@@ -0,0 +1,146 @@
+package query
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Connector is a struct implementing driver.Connector that runs setup queries on every new connection,
+// so that per-connection pragmas and attachments apply to the whole *sql.DB pool
+type Connector struct {
+	connector driver.Connector
+	setup     []statement
+}
+
+// statement is a built query kept for repeated execution
+type statement struct {
+	query string
+	args  []any
+}
+
+// NewConnector is a function that wraps a connector so that the specified queries run on every new connection
+func NewConnector(connector driver.Connector, setup ...*Query) (*Connector, error) {
+	c := &Connector{connector: connector}
+	for _, q := range setup {
+		query, args, err := q.Build()
+		if err != nil {
+			return nil, err
+		}
+		c.setup = append(c.setup, statement{query: query, args: args})
+	}
+	return c, nil
+}
+
+// NewDriverConnector is a function that wraps a driver and data source name so that the specified queries run on every new connection
+func NewDriverConnector(d driver.Driver, dsn string, setup ...*Query) (*Connector, error) {
+	if dc, ok := d.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return NewConnector(connector, setup...)
+	}
+	return NewConnector(dsnConnector{driver: d, dsn: dsn}, setup...)
+}
+
+// Connect is a function that opens a connection and runs the setup queries on it
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range c.setup {
+		if err := execConn(ctx, conn, stmt); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("query: connection setup %q: %w", stmt.query, err)
+		}
+	}
+	return conn, nil
+}
+
+// Driver is a function that returns the underlying driver
+func (c *Connector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// WALProfile is a function that returns the setup queries of a web application database: a busy timeout,
+// the WAL journal with NORMAL synchronous and foreign key enforcement
+func WALProfile(busyTimeout time.Duration) []*Query {
+	return []*Query{
+		PragmaBusyTimeout(busyTimeout),
+		PragmaJournalMode("", JournalModeWAL),
+		PragmaSynchronous("", SynchronousNormal),
+		PragmaForeignKeys(true),
+	}
+}
+
+// ReadOnlyProfile is a function that returns the setup queries of a read-only replica connection:
+// a busy timeout, foreign key enforcement and query_only rejecting any write
+func ReadOnlyProfile(busyTimeout time.Duration) []*Query {
+	return []*Query{
+		PragmaBusyTimeout(busyTimeout),
+		PragmaForeignKeys(true),
+		PragmaSchema("", "query_only", "ON"),
+	}
+}
+
+// dsnConnector is a driver.Connector for drivers that do not implement driver.DriverContext
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// execConn executes a statement directly on a driver connection
+func execConn(ctx context.Context, conn driver.Conn, stmt statement) error {
+	args := make([]driver.NamedValue, len(stmt.args))
+	for i, arg := range stmt.args {
+		value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			return err
+		}
+		args[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+	}
+
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, stmt.query, args)
+		if !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+
+	var (
+		s   driver.Stmt
+		err error
+	)
+	if preparer, ok := conn.(driver.ConnPrepareContext); ok {
+		s, err = preparer.PrepareContext(ctx, stmt.query)
+	} else {
+		s, err = conn.Prepare(stmt.query)
+	}
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if execer, ok := s.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, args)
+		return err
+	}
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	_, err = s.Exec(values)
+	return err
+}
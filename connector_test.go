@@ -0,0 +1,71 @@
+package query_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+func TestConnector(t *testing.T) {
+	_, mock := querytest.New(t)
+	mock.ExpectRegexp("^(PRAGMA|ATTACH) ").Times(-1)
+	connector, err := query.NewConnector(mock.Connector(), append(
+		query.WALProfile(5*time.Second),
+		query.AttachDatabase("archive.db", "archive"),
+	)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	first, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	setup := []string{
+		"PRAGMA busy_timeout = 5000;",
+		"PRAGMA journal_mode = WAL;",
+		"PRAGMA synchronous = NORMAL;",
+		"PRAGMA foreign_keys = ON;",
+		"ATTACH DATABASE 'archive.db' AS archive;",
+	}
+	expected := append(append([]string(nil), setup...), setup...)
+	if statements := mock.Queries(); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Expected statements %q, but got %q", expected, statements)
+	}
+}
+
+func TestConnectorSetupError(t *testing.T) {
+	_, mock := querytest.New(t)
+	mock.Expect("PRAGMA query_only = ON;").Times(-1).WillReturnError(errors.New("boom"))
+	mock.ExpectRegexp("^PRAGMA ").Times(-1)
+
+	connector, err := query.NewConnector(mock.Connector(), query.ReadOnlyProfile(time.Second)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	if err := db.Ping(); err == nil {
+		t.Errorf("Expected the connection setup error")
+	}
+
+	if _, err := query.NewConnector(mock.Connector(), query.PragmaJournalMode("", "BOGUS")); err == nil {
+		t.Errorf("Expected an error for an invalid setup query")
+	}
+}
@@ -0,0 +1,387 @@
+package query
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// TimeFormat is the representation time.Time arguments are converted to
+type TimeFormat int
+
+const (
+	// TimeISO8601 converts times to UTC text in the TimeLayout format, the format of CURRENT_TIMESTAMP with
+	// milliseconds, which compares in time order with it and with other converted times
+	TimeISO8601 TimeFormat = iota
+	// TimeUnix converts times to INTEGER seconds since the Unix epoch
+	TimeUnix
+	// TimeUnixMilli converts times to INTEGER milliseconds since the Unix epoch
+	TimeUnixMilli
+	// TimeJulian converts times to REAL Julian day numbers, as returned by the julianday function
+	TimeJulian
+	// TimeNative passes times to the driver unchanged
+	TimeNative
+)
+
+// TimeLayout is the layout of the times converted with TimeISO8601, understood by SQLite date functions
+const TimeLayout = "2006-01-02 15:04:05.000"
+
+// julianEpoch is the Julian day number of the Unix epoch
+const julianEpoch = 2440587.5
+
+// Converters is a struct holding the conversions applied by Build to the arguments of queries and by Scanner
+// to the values scanned from rows. Times are converted to the Time format; maps, slices, arrays and structs
+// with json tags to JSON text; booleans to 0 and 1 and driver.Valuer values to their value. Types registered
+// with RegisterConverter take precedence.
+type Converters struct {
+	Time TimeFormat
+
+	mu    sync.RWMutex
+	types map[reflect.Type]converter
+}
+
+// converter holds the conversions of a registered type, either of which may be nil
+type converter struct {
+	to   func(any) (driver.Value, error)
+	from func(any) (any, error)
+}
+
+// DefaultConverters is the converter registry used by Build and Scanner
+var DefaultConverters = &Converters{}
+
+// RegisterConverter is a function that registers the conversion of the arguments of type T to a driver value
+// and the conversion of scanned values back to T. Either function may be nil to convert in one direction only.
+func RegisterConverter[T any](c *Converters, to func(T) (driver.Value, error), from func(src any) (T, error)) {
+	var conv converter
+	if to != nil {
+		conv.to = func(v any) (driver.Value, error) { return to(v.(T)) }
+	}
+	if from != nil {
+		conv.from = func(src any) (any, error) { return from(src) }
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.types == nil {
+		c.types = map[reflect.Type]converter{}
+	}
+	c.types[reflect.TypeOf((*T)(nil)).Elem()] = conv
+}
+
+func (c *Converters) lookup(typ reflect.Type) (converter, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	conv, ok := c.types[typ]
+	return conv, ok
+}
+
+// JSONValue is a struct wrapping a value that is converted to JSON text as an argument and decoded from JSON
+// text as a scan destination
+type JSONValue struct {
+	value any
+}
+
+// AsJSON is a function that wraps a value to be stored as JSON text, or a pointer to be scanned from JSON text
+func AsJSON(value any) JSONValue {
+	return JSONValue{value: value}
+}
+
+// Value is a function that implements driver.Valuer and returns the wrapped value as JSON text
+func (v JSONValue) Value() (driver.Value, error) {
+	return marshalJSON(v.value)
+}
+
+// Scan is a function that implements sql.Scanner and decodes JSON text into the wrapped pointer
+func (v JSONValue) Scan(src any) error {
+	return unmarshalJSON(src, v.value)
+}
+
+// Convert is a function that converts an argument to the value passed to the driver. The names of
+// sql.NamedArg and the redaction of SensitiveValue arguments are kept.
+func (c *Converters) Convert(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, string, []byte, int64, float64:
+		return value, nil
+	case sql.NamedArg:
+		converted, err := c.Convert(v.Value)
+		return sql.Named(v.Name, converted), err
+	case SensitiveValue:
+		converted, err := c.Convert(v.value)
+		return SensitiveValue{value: converted}, err
+	case *SensitiveValue:
+		if v == nil {
+			return nil, nil
+		}
+		return c.Convert(*v)
+	case JSONValue:
+		return v.Value()
+	}
+
+	if conv, ok := c.lookup(reflect.TypeOf(value)); ok && conv.to != nil {
+		converted, err := conv.to(value)
+		if err != nil {
+			return nil, err
+		}
+		return c.basic(converted), nil
+	}
+	if valuer, ok := value.(driver.Valuer); ok {
+		converted, err := callValuer(valuer)
+		if err != nil {
+			return nil, err
+		}
+		return c.basic(converted), nil
+	}
+	switch value.(type) {
+	case time.Time, bool:
+		return c.basic(value), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return c.Convert(rv.Elem().Interface())
+	case reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+		return marshalJSON(value)
+	case reflect.Array:
+		// fixed-size byte arrays such as UUIDs are stored as blobs
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return b, nil
+		}
+		return marshalJSON(value)
+	case reflect.Struct:
+		if hasJSONTags(rv.Type()) {
+			return marshalJSON(value)
+		}
+	}
+	return value, nil
+}
+
+// basic converts times and booleans, returning any other value unchanged
+func (c *Converters) basic(value any) any {
+	switch v := value.(type) {
+	case time.Time:
+		switch c.Time {
+		case TimeUnix:
+			return v.Unix()
+		case TimeUnixMilli:
+			return v.UnixMilli()
+		case TimeJulian:
+			return float64(v.Unix())/86400 + float64(v.Nanosecond())/float64(24*time.Hour) + julianEpoch
+		case TimeNative:
+			return v
+		}
+		return v.UTC().Format(TimeLayout)
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	}
+	return value
+}
+
+// convertArgs converts the arguments of a built query
+func (c *Converters) convertArgs(args []any) error {
+	for i, arg := range args {
+		converted, err := c.Convert(arg)
+		if err != nil {
+			return fmt.Errorf("query: converting argument %d: %w", i+1, err)
+		}
+		args[i] = converted
+	}
+	return nil
+}
+
+// hasJSONTags reports whether a struct has a field with a json tag
+func hasJSONTags(typ reflect.Type) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		if _, ok := typ.Field(i).Tag.Lookup("json"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func marshalJSON(value any) (driver.Value, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func unmarshalJSON(src, dest any) error {
+	switch s := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(s), dest)
+	case []byte:
+		return json.Unmarshal(s, dest)
+	}
+	return fmt.Errorf("query: cannot decode JSON from %T", src)
+}
+
+// Scanner is a function that returns a scan destination converting values into dest with DefaultConverters
+func Scanner(dest any) sql.Scanner {
+	return DefaultConverters.Scanner(dest)
+}
+
+// Scanner is a function that returns a scan destination applying the reverse conversions into dest, which
+// must be a pointer. Times are parsed from text, Unix times or Julian day numbers, JSON text is decoded into
+// maps, slices, arrays and structs with json tags, and NULL sets dest to its zero value.
+func (c *Converters) Scanner(dest any) sql.Scanner {
+	return scanner{c: c, dest: dest}
+}
+
+type scanner struct {
+	c    *Converters
+	dest any
+}
+
+// Scan is a function that implements sql.Scanner
+func (s scanner) Scan(src any) error {
+	rv := reflect.ValueOf(s.dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("query: scan destination %T is not a non-nil pointer", s.dest)
+	}
+	elem := rv.Elem()
+	if conv, ok := s.c.lookup(elem.Type()); ok && conv.from != nil {
+		v, err := conv.from(src)
+		if err != nil {
+			return err
+		}
+		elem.Set(reflect.ValueOf(v))
+		return nil
+	}
+	if sc, ok := s.dest.(sql.Scanner); ok {
+		return sc.Scan(src)
+	}
+	if src == nil {
+		elem.SetZero()
+		return nil
+	}
+
+	switch d := s.dest.(type) {
+	case *time.Time:
+		t, err := s.c.parseTime(src)
+		if err != nil {
+			return err
+		}
+		*d = t
+		return nil
+	case *bool:
+		switch v := src.(type) {
+		case bool:
+			*d = v
+			return nil
+		case int64:
+			*d = v != 0
+			return nil
+		}
+		return fmt.Errorf("query: cannot scan %T into %T", src, s.dest)
+	}
+
+	switch elem.Kind() {
+	case reflect.Pointer:
+		ptr := reflect.New(elem.Type().Elem())
+		if err := (scanner{c: s.c, dest: ptr.Interface()}).Scan(src); err != nil {
+			return err
+		}
+		elem.Set(ptr)
+		return nil
+	case reflect.Slice:
+		if elem.Type().Elem().Kind() != reflect.Uint8 {
+			return unmarshalJSON(src, s.dest)
+		}
+	case reflect.Map:
+		return unmarshalJSON(src, s.dest)
+	case reflect.Array:
+		if elem.Type().Elem().Kind() != reflect.Uint8 {
+			return unmarshalJSON(src, s.dest)
+		}
+		if b, ok := src.([]byte); ok && len(b) == elem.Len() {
+			reflect.Copy(elem, reflect.ValueOf(b))
+			return nil
+		}
+	case reflect.Struct:
+		if hasJSONTags(elem.Type()) {
+			return unmarshalJSON(src, s.dest)
+		}
+	}
+	return assign(elem, src)
+}
+
+// assign sets a scanned value of a kind compatible with the destination
+func assign(dest reflect.Value, src any) error {
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(dest.Type()) {
+		dest.Set(sv)
+		return nil
+	}
+	if compatible(sv.Kind(), dest.Kind()) && sv.Type().ConvertibleTo(dest.Type()) {
+		dest.Set(sv.Convert(dest.Type()))
+		return nil
+	}
+	return fmt.Errorf("query: cannot scan %T into %s", src, dest.Type())
+}
+
+// compatible reports whether a value of kind src converts to kind dest without changing its meaning
+func compatible(src, dest reflect.Kind) bool {
+	numeric := func(k reflect.Kind) bool { return k >= reflect.Int && k <= reflect.Float64 }
+	text := func(k reflect.Kind) bool { return k == reflect.String || k == reflect.Slice }
+	return numeric(src) && numeric(dest) || text(src) && text(dest) || src == dest
+}
+
+// timeLayouts are the layouts of the times parsed from text, as accepted by SQLite date functions
+var timeLayouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTime converts a scanned value to a time, reading integers in the Unix format of the converters
+func (c *Converters) parseTime(src any) (time.Time, error) {
+	switch v := src.(type) {
+	case time.Time:
+		return v, nil
+	case int64:
+		if c.Time == TimeUnixMilli {
+			return time.UnixMilli(v).UTC(), nil
+		}
+		return time.Unix(v, 0).UTC(), nil
+	case float64:
+		micros := math.Round((v - julianEpoch) * float64(24*time.Hour/time.Microsecond))
+		return time.UnixMicro(int64(micros)).UTC(), nil
+	case []byte:
+		return c.parseTime(string(v))
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("query: cannot parse time %q", v)
+	}
+	return time.Time{}, fmt.Errorf("query: cannot scan %T into a time", src)
+}
@@ -0,0 +1,184 @@
+package query_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinytoolkit/query"
+)
+
+type profile struct {
+	Theme string   `json:"theme"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+type point struct{ X, Y int }
+
+func TestConvert(t *testing.T) {
+	created := time.Date(2024, 5, 6, 7, 8, 9, 500000000, time.FixedZone("CEST", 2*60*60))
+	var missing *int
+	tests := []struct {
+		value    any
+		expected any
+	}{
+		{nil, nil},
+		{"a", "a"},
+		{42, 42},
+		{true, int64(1)},
+		{false, int64(0)},
+		{created, "2024-05-06 05:08:09.500"},
+		{&created, "2024-05-06 05:08:09.500"},
+		{missing, nil},
+		{sql.NullString{String: "x", Valid: true}, "x"},
+		{sql.NullTime{}, nil},
+		{map[string]int{"a": 1}, `{"a":1}`},
+		{[]string{"a", "b"}, `["a","b"]`},
+		{[]string(nil), nil},
+		{[]byte("raw"), []byte("raw")},
+		{[4]byte{1, 2, 3, 4}, []byte{1, 2, 3, 4}},
+		{profile{Theme: "dark"}, `{"theme":"dark"}`},
+		{query.AsJSON(point{1, 2}), `{"X":1,"Y":2}`},
+		{point{1, 2}, point{1, 2}},
+		{sql.Named("at", created), sql.Named("at", "2024-05-06 05:08:09.500")},
+	}
+
+	for _, test := range tests {
+		converted, err := query.DefaultConverters.Convert(test.value)
+		if err != nil {
+			t.Errorf("Expected no error converting %#v, but got %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(converted, test.expected) {
+			t.Errorf("Expected %#v to convert to %#v, but got %#v", test.value, test.expected, converted)
+		}
+	}
+}
+
+func TestConvertTimeFormats(t *testing.T) {
+	created := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		format   query.TimeFormat
+		expected any
+	}{
+		{query.TimeISO8601, "2024-05-06 12:00:00.000"},
+		{query.TimeUnix, created.Unix()},
+		{query.TimeUnixMilli, created.UnixMilli()},
+		{query.TimeJulian, 2460437.0},
+		{query.TimeNative, created},
+	}
+
+	for _, test := range tests {
+		c := &query.Converters{Time: test.format}
+		converted, err := c.Convert(created)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(converted, test.expected) {
+			t.Errorf("Expected format %d to convert to %#v, but got %#v", test.format, test.expected, converted)
+		}
+
+		var scanned time.Time
+		if err := c.Scanner(&scanned).Scan(converted); err != nil {
+			t.Fatal(err)
+		}
+		if !scanned.Equal(created) {
+			t.Errorf("Expected format %d to scan back %v, but got %v", test.format, created, scanned)
+		}
+	}
+}
+
+func TestBuildConvertsArgs(t *testing.T) {
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	_, args, err := query.InsertInto("users").Columns("name", "active", "created_at", "profile").
+		Values("alice", true, created, profile{Theme: "dark"}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []any{"alice", int64(1), "2024-05-06 07:08:09.000", `{"theme":"dark"}`}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected args %#v, but got %#v", expected, args)
+	}
+
+	_, args, err = query.Update("users", "").Set([]*query.Field{{Name: "tags", Value: []string{"a"}}}).
+		Where("").In("id", query.Sensitive(true)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[0] != `["a"]` || query.Literal(args[1]) != query.RedactedLiteral {
+		t.Errorf("Expected the slice as JSON and the sensitive argument kept redacted, but got %#v", args)
+	}
+}
+
+func TestRegisterConverter(t *testing.T) {
+	c := &query.Converters{}
+	query.RegisterConverter(c,
+		func(p point) (driver.Value, error) { return p.X*1000 + p.Y, nil },
+		func(src any) (point, error) {
+			n, ok := src.(int64)
+			if !ok {
+				return point{}, errors.New("not an integer")
+			}
+			return point{int(n / 1000), int(n % 1000)}, nil
+		})
+
+	converted, err := c.Convert(point{3, 4})
+	if err != nil || converted != 3004 {
+		t.Errorf("Expected point to convert to 3004, but got %#v %v", converted, err)
+	}
+	var p point
+	if err := c.Scanner(&p).Scan(int64(5006)); err != nil || p != (point{5, 6}) {
+		t.Errorf("Expected 5006 to scan to {5 6}, but got %v %v", p, err)
+	}
+	if err := c.Scanner(&p).Scan("x"); err == nil {
+		t.Error("Expected an error from the registered conversion")
+	}
+}
+
+func TestScanner(t *testing.T) {
+	var (
+		created time.Time
+		pref    profile
+		tags    []string
+		doc     point
+		active  bool
+		name    string
+		count   int
+		ptr     *string
+	)
+	scans := []struct {
+		dest sql.Scanner
+		src  any
+	}{
+		{query.Scanner(&created), "2024-05-06 07:08:09"},
+		{query.Scanner(&pref), `{"theme":"dark","tags":["a"]}`},
+		{query.Scanner(&tags), []byte(`["x","y"]`)},
+		{query.AsJSON(&doc), `{"X":1,"Y":2}`},
+		{query.Scanner(&active), int64(1)},
+		{query.Scanner(&name), []byte("bob")},
+		{query.Scanner(&count), int64(7)},
+		{query.Scanner(&ptr), "p"},
+	}
+	for _, scan := range scans {
+		if err := scan.dest.Scan(scan.src); err != nil {
+			t.Fatalf("Expected no error scanning %#v, but got %v", scan.src, err)
+		}
+	}
+
+	if !created.Equal(time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)) || pref.Theme != "dark" || len(pref.Tags) != 1 ||
+		strings.Join(tags, ",") != "x,y" || doc != (point{1, 2}) || !active || name != "bob" || count != 7 ||
+		ptr == nil || *ptr != "p" {
+		t.Errorf("Unexpected scanned values %v %v %v %v %v %q %d %v", created, pref, tags, doc, active, name, count, ptr)
+	}
+
+	if err := query.Scanner(&ptr).Scan(nil); err != nil || ptr != nil {
+		t.Errorf("Expected NULL to scan to a nil pointer, but got %v %v", ptr, err)
+	}
+	if err := query.Scanner(&count).Scan("seven"); err == nil {
+		t.Error("Expected an error scanning text into an int")
+	}
+}
@@ -0,0 +1,48 @@
+package query
+
+import "strings"
+
+// CountQuery is a function that returns a new query counting the rows of the select query, without resetting
+// it: the query is wrapped as SELECT COUNT(*) FROM (...) once its trailing ORDER BY, LIMIT and OFFSET clauses
+// and their arguments are removed, so that a list and its total are built from one definition
+func (q *Query) CountQuery() *Query {
+	query := string(q.query)
+	tokens := tokenize(query)
+	pos, depth := 0, 0
+	for i, tok := range tokens {
+		if tok.text == "(" {
+			depth++
+		} else if tok.text == ")" {
+			depth--
+		} else if depth == 0 && tok.kind == tokenWord && (strings.EqualFold(tok.text, "LIMIT") || strings.EqualFold(tok.text, "OFFSET") ||
+			strings.EqualFold(tok.text, "ORDER") && strings.EqualFold(nextWord(tokens[i+1:]), "BY")) {
+			query = query[:pos]
+			break
+		}
+		pos += len(tok.text)
+	}
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+
+	args := q.args
+	if n := Placeholders(query); n < len(args) {
+		args = args[:n]
+	}
+
+	count := getQuery()
+	count.query = append(count.query, "SELECT COUNT(*) FROM ("...)
+	count.query = append(count.query, query...)
+	count.query = append(count.query, ')')
+	count.args = append(count.args, args...)
+	count.err = q.err
+	return count
+}
+
+// nextWord returns the text of the first token that is not a space or a comment
+func nextWord(tokens []token) string {
+	for _, tok := range tokens {
+		if tok.kind != tokenSpace && tok.kind != tokenComment {
+			return tok.text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package query_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+func TestCountQuery(t *testing.T) {
+	tests := []struct {
+		q        *query.Query
+		expected string
+		args     []any
+	}{
+		{
+			query.Select("users.*").From("users").Join("teams", "teams.id = users.team_id").
+				Where("teams.name = ? AND users.age > ?").Args("core", 30).OrderBy("users.name").Limit(20).Offset(40),
+			"SELECT COUNT(*) FROM (SELECT users.* FROM users JOIN teams ON teams.id = users.team_id WHERE teams.name = ? AND users.age > ?)",
+			[]any{"core", 30},
+		},
+		{
+			query.Select("team_id", "COUNT(*)").From("users").GroupBy("team_id").Having("COUNT(*) > ?").Args(2).Paginate(3, 10),
+			"SELECT COUNT(*) FROM (SELECT team_id, COUNT(*) FROM users GROUP BY team_id HAVING COUNT(*) > ?)",
+			[]any{2},
+		},
+		{
+			query.With("recent", query.Select("*").From("posts").OrderBy("id").Desc().Limit(100)).
+				Select("id", "row_number() OVER (ORDER BY id)").From("recent").Where("author = ?").Args("ann"),
+			"SELECT COUNT(*) FROM (WITH recent AS (SELECT * FROM posts ORDER BY id DESC LIMIT ?) " +
+				"SELECT id, row_number() OVER (ORDER BY id) FROM recent WHERE author = ?)",
+			[]any{100, "ann"},
+		},
+		{
+			query.Select("*").From("posts").Page(query.Keyset{Keys: []query.SortKey{{Column: "id"}}, Limit: 5}, query.Cursor{Values: []any{9}}),
+			"SELECT COUNT(*) FROM (SELECT * FROM posts WHERE id > ?)",
+			[]any{9},
+		},
+	}
+
+	for _, test := range tests {
+		count, args, err := test.q.CountQuery().Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != test.expected {
+			t.Errorf("Expected query '%s', but got '%s'", test.expected, count)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("Expected args %v, but got %v", test.args, args)
+		}
+		test.q.Reset()
+	}
+}
+
+func TestCountQueryKeepsQuery(t *testing.T) {
+	q := query.Select("*").From("users").Where("active = ?").Args(1).Limit(10)
+	count := q.CountQuery()
+	if s := count.String(); s != "SELECT COUNT(*) FROM (SELECT * FROM users WHERE active = ?)" {
+		t.Errorf("Unexpected count query '%s'", s)
+	}
+
+	s, args, err := q.Build()
+	if err != nil || s != "SELECT * FROM users WHERE active = ? LIMIT ?" || len(args) != 2 {
+		t.Errorf("Expected the select query to be kept, but got '%s' %v %v", s, args, err)
+	}
+}
@@ -0,0 +1,184 @@
+package query
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// RedactedLiteral is the literal rendered by Debug and Interpolate in place of a sensitive argument
+const RedactedLiteral = "'[REDACTED]'"
+
+// SensitiveValue is a struct wrapping an argument that is executed as is but redacted by Debug and Interpolate
+type SensitiveValue struct {
+	value any
+}
+
+// Sensitive is a function that flags an argument as sensitive, such as a password or a token, so that it
+// never appears in a rendered query
+func Sensitive(value any) SensitiveValue {
+	return SensitiveValue{value: value}
+}
+
+// Value is a function that implements driver.Valuer and returns the wrapped argument
+func (v SensitiveValue) Value() (driver.Value, error) {
+	if valuer, ok := v.value.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v.value)
+}
+
+// Debug is a function that returns the query string with its arguments inlined as SQLite literals, without
+// resetting the query. The result is meant for logs and the sqlite3 shell, never for execution.
+func (q *Query) Debug() string {
+	return Interpolate(string(q.query), q.args...)
+}
+
+// Interpolate is a function that returns the query string with the arguments inlined as SQLite literals in
+// place of the ?, ?NNN, :name, @name and $name parameters, following the numbering rules of SQLite.
+// Parameters without a matching argument are kept as is. Arguments are converted with DefaultConverters first,
+// so that the literals show the values Build binds. The result must never be executed.
+func Interpolate(query string, args ...any) string {
+	var (
+		buf    []byte
+		params parameters
+	)
+	args = debugArgs(args)
+	for _, tok := range tokenize(query) {
+		if tok.kind != tokenParam {
+			buf = append(buf, tok.text...)
+			continue
+		}
+
+		i := params.number(tok.text)
+		arg, found := namedArg(tok.text, args)
+		if !found && i >= 1 && i <= len(args) {
+			arg, found = args[i-1], true
+		}
+		if !found {
+			buf = append(buf, tok.text...)
+			continue
+		}
+		buf = append(buf, Literal(arg)...)
+	}
+	return string(buf)
+}
+
+// debugArgs returns a copy of the arguments converted with DefaultConverters, keeping the ones that fail
+func debugArgs(args []any) []any {
+	converted := make([]any, len(args))
+	for i, arg := range args {
+		value, err := DefaultConverters.Convert(arg)
+		if err != nil {
+			value = arg
+		}
+		converted[i] = value
+	}
+	return converted
+}
+
+// namedArg returns the sql.NamedArg bound to a named parameter
+func namedArg(param string, args []any) (any, bool) {
+	if param[0] == '?' {
+		return nil, false
+	}
+	for _, arg := range args {
+		if na, ok := arg.(sql.NamedArg); ok && na.Name == param[1:] {
+			return na, true
+		}
+	}
+	return nil, false
+}
+
+// Literal is a function that renders a value as an SQLite literal: NULL, a number, a quoted string or an
+// X'..' blob. Booleans render as 1 and 0 and times as text in the format understood by SQLite date functions.
+func Literal(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case SensitiveValue, *SensitiveValue:
+		return RedactedLiteral
+	case sql.NamedArg:
+		return Literal(v.Value)
+	case string:
+		return quoteLiteral(v)
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return formatFloat(float64(v), 32)
+	case float64:
+		return formatFloat(v, 64)
+	case time.Time:
+		return quoteLiteral(v.Format("2006-01-02 15:04:05.999999999-07:00"))
+	case driver.Valuer:
+		value, err := callValuer(v)
+		if err != nil {
+			return "NULL"
+		}
+		return Literal(value)
+	}
+
+	converted, err := driver.DefaultParameterConverter.ConvertValue(value)
+	if err != nil {
+		return quoteLiteral(fmt.Sprint(value))
+	}
+	return Literal(converted)
+}
+
+// formatFloat renders a float, using the 9e999 overflow SQLite reads as infinity
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NULL"
+	case math.IsInf(f, 1):
+		return "9e999"
+	case math.IsInf(f, -1):
+		return "-9e999"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '.' || s[i] == 'e' {
+			return s
+		}
+	}
+	// keep the value a REAL rather than an INTEGER literal
+	return s + ".0"
+}
+
+// callValuer calls Value, treating a nil pointer as NULL
+func callValuer(v driver.Valuer) (driver.Value, error) {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil, nil
+	}
+	return v.Value()
+}
@@ -0,0 +1,60 @@
+package query_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/tinytoolkit/query"
+)
+
+func TestDebug(t *testing.T) {
+	q := query.Select("*").From("users").Where("name = ? AND active = ?").Args("O'Brien", true)
+	expected := "SELECT * FROM users WHERE name = 'O''Brien' AND active = 1"
+	if debug := q.Debug(); debug != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, debug)
+	}
+
+	// Debug does not reset the query
+	query, args := q.Query()
+	if query != "SELECT * FROM users WHERE name = ? AND active = ?" || len(args) != 2 {
+		t.Errorf("Expected the query to be kept, but got '%s' %v", query, args)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	created := time.Date(2024, 5, 6, 7, 8, 9, 500000000, time.UTC)
+	var missing *sql.NullString
+	secret := query.Sensitive("hunter2")
+	tests := []struct {
+		query    string
+		args     []any
+		expected string
+	}{
+		{"SELECT ?, ?, ?, ?", []any{nil, 42, 1.5, 2.0}, "SELECT NULL, 42, 1.5, 2.0"},
+		{"INSERT INTO files (data) VALUES (?)", []any{[]byte{0xde, 0xad}}, "INSERT INTO files (data) VALUES (X'dead')"},
+		{"SELECT ?", []any{created}, "SELECT '2024-05-06 07:08:09.500'"},
+		{"SELECT ?, ?", []any{map[string]int{"a": 1}, []int{1, 2}}, `SELECT '{"a":1}', '[1,2]'`},
+		{"SELECT ?, ?", []any{sql.NullInt64{Int64: 3, Valid: true}, missing}, "SELECT 3, NULL"},
+		{"SELECT '?', \"?\", ? -- ?\n/* ? */", []any{1}, "SELECT '?', \"?\", 1 -- ?\n/* ? */"},
+		{"SELECT ?2, ?1, ?", []any{"a", "b", "c"}, "SELECT 'b', 'a', 'c'"},
+		{"SELECT :a, @b, :a", []any{1, 2}, "SELECT 1, 2, 1"},
+		{"SELECT :b, :a", []any{sql.Named("a", 1), sql.Named("b", 2)}, "SELECT 2, 1"},
+		{"SELECT ?, ?", []any{1}, "SELECT 1, ?"},
+		{"UPDATE users SET password = ? WHERE id = ?", []any{query.Sensitive("hunter2"), 7}, "UPDATE users SET password = '[REDACTED]' WHERE id = 7"},
+		{"UPDATE users SET password = ? WHERE id = ?", []any{&secret, 7}, "UPDATE users SET password = '[REDACTED]' WHERE id = 7"},
+	}
+	for _, test := range tests {
+		q := query.Interpolate(test.query, test.args...)
+		if q != test.expected {
+			t.Errorf("Expected query '%s', but got '%s'", test.expected, q)
+		}
+	}
+}
+
+func TestSensitive(t *testing.T) {
+	value, err := query.Sensitive("hunter2").Value()
+	if err != nil || value != "hunter2" {
+		t.Errorf("Expected the wrapped value, but got %v (%v)", value, err)
+	}
+}
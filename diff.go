@@ -0,0 +1,455 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffOptions is a struct holding the options of Diff
+type DiffOptions struct {
+	// AllowDestructive allows changes that drop tables or columns or rebuild tables
+	AllowDestructive bool
+}
+
+// DestructiveError is an error returned by Diff when converging requires destructive changes that were not allowed
+type DestructiveError struct {
+	Changes []string
+}
+
+// Error is a function that returns the error message listing the destructive changes
+func (e *DestructiveError) Error() string {
+	return "query: destructive schema changes not allowed: " + strings.Join(e.Changes, "; ")
+}
+
+// Migration is a struct holding the statements converging a live schema onto a desired one. They must run on a
+// single connection, since PRAGMA foreign_keys only applies to the connection running it.
+type Migration struct {
+	// Before holds the statements to run before the transaction, which have no effect within it
+	Before []*Query
+	// Statements holds the statements to run in a single transaction. When tables are rebuilt the last one is
+	// PRAGMA foreign_key_check, whose rows list the foreign keys the migration broke: the transaction must be
+	// rolled back rather than committed when it returns any.
+	Statements []*Query
+	// After holds the statements to run once the transaction is committed or rolled back
+	After []*Query
+}
+
+// Diff is a function that returns the migration converging the live schema onto the desired one.
+// Columns are compared on the attributes introspection can read back: type, NOT NULL, UNIQUE, DEFAULT and REFERENCES.
+// Table changes ALTER TABLE cannot make rebuild the table following the SQLite procedure, which disables foreign
+// keys around the transaction. Diff cannot tell whether a live table holds rows, so it returns an error when a
+// rebuild adds a NOT NULL column without a default, which copying the rows of a populated table would fail on.
+func Diff(desired, live *Schema, opts DiffOptions) (Migration, error) {
+	changes, err := diffSchemas(desired, live)
+	if err != nil {
+		release(changes)
+		return Migration{}, err
+	}
+
+	var destructive []string
+	for _, c := range changes {
+		if c.destructive {
+			destructive = append(destructive, c.summary)
+		}
+	}
+	if len(destructive) > 0 && !opts.AllowDestructive {
+		release(changes)
+		return Migration{}, &DestructiveError{Changes: destructive}
+	}
+
+	var m Migration
+	for _, c := range changes {
+		switch c.stage {
+		case stageBefore:
+			m.Before = append(m.Before, c.queries...)
+		case stageAfter:
+			m.After = append(m.After, c.queries...)
+		default:
+			m.Statements = append(m.Statements, c.queries...)
+		}
+	}
+	return m, nil
+}
+
+// Drift is a function that describes the differences between the desired and live schemas, empty when they match
+func Drift(desired, live *Schema) []string {
+	changes, _ := diffSchemas(desired, live)
+	var drift []string
+	for _, c := range changes {
+		// steps without a summary complete another change
+		if c.summary != "" {
+			drift = append(drift, c.summary)
+		}
+	}
+	release(changes)
+	return drift
+}
+
+// release returns the queries of changes that are not returned to the pool
+func release(changes []change) {
+	for _, c := range changes {
+		for _, q := range c.queries {
+			q.Reset()
+		}
+	}
+}
+
+// change is a single step of a schema migration
+type change struct {
+	summary     string
+	destructive bool
+	stage       int
+	queries     []*Query
+}
+
+// the stages of a migration around its transaction
+const (
+	stageTx = iota
+	stageBefore
+	stageAfter
+)
+
+// the phases of a migration, in the order their statements must run
+const (
+	phaseDisableForeignKeys = iota
+	phaseDropTriggers
+	phaseDropViews
+	phaseDropIndexes
+	phaseCreateTables
+	phaseAddColumns
+	phaseRebuildTables
+	phaseDropColumns
+	phaseCreateIndexes
+	phaseDropTables
+	phaseCreateViews
+	phaseCreateTriggers
+	phaseCheckForeignKeys
+	phaseCount
+)
+
+type differ struct {
+	phases [phaseCount][]change
+	// err is the first change that cannot be made
+	err error
+}
+
+func (d *differ) add(phase int, summary string, destructive bool, queries ...*Query) {
+	d.phases[phase] = append(d.phases[phase], change{summary: summary, destructive: destructive, queries: queries})
+}
+
+func diffSchemas(desired, live *Schema) ([]change, error) {
+	d := &differ{}
+	rebuilt := map[string]bool{}
+
+	liveTables := map[string]Table{}
+	for _, table := range live.Tables {
+		liveTables[strings.ToLower(table.Name)] = table
+	}
+	desiredTables := map[string]bool{}
+	for _, table := range desired.Tables {
+		desiredTables[strings.ToLower(table.Name)] = true
+		current, ok := liveTables[strings.ToLower(table.Name)]
+		if !ok {
+			d.add(phaseCreateTables, "create table "+table.Name, false, CreateTableFrom(table))
+			continue
+		}
+		if d.diffTable(table, current) {
+			rebuilt[strings.ToLower(table.Name)] = true
+		}
+	}
+	for _, table := range live.Tables {
+		if !desiredTables[strings.ToLower(table.Name)] {
+			d.add(phaseDropTables, "drop table "+table.Name, true, DropTable(table.Name))
+		}
+	}
+
+	liveViews := map[string]View{}
+	for _, view := range live.Views {
+		liveViews[strings.ToLower(view.Name)] = view
+	}
+	desiredViews := map[string]bool{}
+	for _, view := range desired.Views {
+		desiredViews[strings.ToLower(view.Name)] = true
+		current, ok := liveViews[strings.ToLower(view.Name)]
+		switch {
+		case !ok:
+			d.add(phaseCreateViews, "create view "+view.Name, false, createView(view))
+		case normalizeSQL(viewSQL(view)) != normalizeSQL(viewSQL(current)):
+			d.add(phaseDropViews, "change view "+view.Name, false, DropView(view.Name))
+			d.add(phaseCreateViews, "", false, createView(view))
+		case len(rebuilt) > 0:
+			// renaming the rebuilt table fails while views still refer to it
+			d.add(phaseDropViews, "", false, DropView(view.Name))
+			d.add(phaseCreateViews, "", false, createView(view))
+		}
+	}
+	for _, view := range live.Views {
+		if !desiredViews[strings.ToLower(view.Name)] {
+			d.add(phaseDropViews, "drop view "+view.Name, false, DropView(view.Name))
+		}
+	}
+
+	liveTriggers := map[string]Trigger{}
+	for _, trigger := range live.Triggers {
+		liveTriggers[strings.ToLower(trigger.Name)] = trigger
+	}
+	desiredTriggers := map[string]bool{}
+	for _, trigger := range desired.Triggers {
+		desiredTriggers[strings.ToLower(trigger.Name)] = true
+		current, ok := liveTriggers[strings.ToLower(trigger.Name)]
+		switch {
+		case !ok:
+			d.add(phaseCreateTriggers, "create trigger "+trigger.Name, false, createTrigger(trigger))
+		case normalizeSQL(triggerSQL(trigger)) != normalizeSQL(triggerSQL(current)):
+			d.add(phaseDropTriggers, "change trigger "+trigger.Name, false, DropTrigger(trigger.Name))
+			d.add(phaseCreateTriggers, "", false, createTrigger(trigger))
+		case rebuilt[strings.ToLower(trigger.Table)]:
+			// dropping the rebuilt table dropped its triggers
+			d.add(phaseCreateTriggers, "", false, createTrigger(trigger))
+		}
+	}
+	for _, trigger := range live.Triggers {
+		if !desiredTriggers[strings.ToLower(trigger.Name)] && !rebuilt[strings.ToLower(trigger.Table)] {
+			d.add(phaseDropTriggers, "drop trigger "+trigger.Name, false, DropTrigger(trigger.Name))
+		}
+	}
+
+	if len(rebuilt) > 0 {
+		// dropping a rebuilt table must neither delete nor fail on the rows referring to it
+		d.phases[phaseDisableForeignKeys] = append(d.phases[phaseDisableForeignKeys],
+			change{stage: stageBefore, queries: []*Query{PragmaForeignKeys(false)}})
+		d.add(phaseCheckForeignKeys, "", false, PragmaSchema("", "foreign_key_check", ""))
+		d.phases[phaseCheckForeignKeys] = append(d.phases[phaseCheckForeignKeys],
+			change{stage: stageAfter, queries: []*Query{PragmaForeignKeys(true)}})
+	}
+
+	var changes []change
+	for _, phase := range d.phases {
+		changes = append(changes, phase...)
+	}
+	return changes, d.err
+}
+
+// diffTable adds the changes converging the live table onto the desired one and reports whether the table is rebuilt
+func (d *differ) diffTable(desired, live Table) bool {
+	liveColumns := map[string]Column{}
+	for _, column := range live.Columns {
+		liveColumns[strings.ToLower(column.Name)] = column
+	}
+	desiredColumns := map[string]bool{}
+
+	rebuild := !sameNames(primaryKey(desired), primaryKey(live)) ||
+		desired.WithoutRowID != live.WithoutRowID ||
+		desired.Strict != live.Strict ||
+		!sameConstraints(desired.Unique, live.Unique)
+	var added, dropped []Column
+	for _, column := range desired.Columns {
+		desiredColumns[strings.ToLower(column.Name)] = true
+		current, ok := liveColumns[strings.ToLower(column.Name)]
+		switch {
+		case !ok:
+			// ADD COLUMN cannot add keys, unique columns, NOT NULL columns without a default, columns with a
+			// default that is not constant or foreign keys with a default that is not NULL
+			if column.PrimaryKey || column.Unique || column.NotNull && column.Default == "" ||
+				!constantDefault(column.Default) ||
+				column.References != "" && column.Default != "" && !strings.EqualFold(column.Default, "NULL") {
+				rebuild = true
+			}
+			added = append(added, column)
+		case !sameColumn(column, current):
+			rebuild = true
+		}
+	}
+	for _, column := range live.Columns {
+		if desiredColumns[strings.ToLower(column.Name)] {
+			continue
+		}
+		// DROP COLUMN cannot drop keys, unique columns or foreign keys
+		if column.PrimaryKey || column.Unique || column.References != "" {
+			rebuild = true
+		}
+		dropped = append(dropped, column)
+	}
+
+	if rebuild {
+		d.rebuildTable(desired, live)
+		return true
+	}
+
+	for _, column := range added {
+		d.add(phaseAddColumns, "add column "+desired.Name+"."+column.Name, false, AlterTable(desired.Name).AddColumn(column))
+	}
+	for _, column := range dropped {
+		d.add(phaseDropColumns, "drop column "+desired.Name+"."+column.Name, true, AlterTable(desired.Name).DropColumn(column.Name))
+	}
+
+	liveIndexes := map[string]Index{}
+	for _, index := range live.Indexes {
+		liveIndexes[strings.ToLower(index.Name)] = index
+	}
+	desiredIndexes := map[string]bool{}
+	for _, index := range desired.Indexes {
+		desiredIndexes[strings.ToLower(index.Name)] = true
+		current, ok := liveIndexes[strings.ToLower(index.Name)]
+		switch {
+		case !ok:
+			d.add(phaseCreateIndexes, "create index "+index.Name, false, CreateIndex(index.Name, desired.Name, index.Columns, index.Unique))
+		case index.Unique != current.Unique || !sameNames(index.Columns, current.Columns):
+			d.add(phaseDropIndexes, "change index "+index.Name, false, DropIndex(index.Name))
+			d.add(phaseCreateIndexes, "", false, CreateIndex(index.Name, desired.Name, index.Columns, index.Unique))
+		}
+	}
+	for _, index := range live.Indexes {
+		if !desiredIndexes[strings.ToLower(index.Name)] {
+			d.add(phaseDropIndexes, "drop index "+index.Name, false, DropIndex(index.Name))
+		}
+	}
+	return false
+}
+
+// rebuildTable adds the statements recreating a table with the desired definition and copying the shared columns over
+func (d *differ) rebuildTable(desired, live Table) {
+	temporary := desired
+	temporary.Name = desired.Name + "_new"
+	temporary.Indexes = nil
+
+	var shared []string
+	for _, column := range desired.Columns {
+		found := false
+		for _, current := range live.Columns {
+			if strings.EqualFold(column.Name, current.Name) {
+				shared = append(shared, column.Name)
+				found = true
+			}
+		}
+		// the copied rows have no value for the new column, and an INTEGER PRIMARY KEY gets their rowid
+		rowid := column.PrimaryKey && strings.EqualFold(column.Type, "INTEGER")
+		if !found && column.NotNull && column.Default == "" && !rowid && d.err == nil {
+			d.err = fmt.Errorf("query: cannot rebuild table %s: new column %s is NOT NULL without a default", desired.Name, column.Name)
+		}
+	}
+
+	queries := []*Query{CreateTableFrom(temporary)}
+	if len(shared) > 0 {
+		columns := strings.Join(shared, ", ")
+		queries = append(queries, InsertInto(temporary.Name).Columns(shared...).Raw(" SELECT "+columns+" FROM "+live.Name+";"))
+	}
+	queries = append(queries,
+		DropTable(live.Name),
+		AlterTable(temporary.Name).RenameTo(desired.Name),
+	)
+	for _, index := range desired.Indexes {
+		queries = append(queries, CreateIndex(index.Name, desired.Name, index.Columns, index.Unique))
+	}
+	d.add(phaseRebuildTables, "rebuild table "+desired.Name, true, queries...)
+}
+
+// primaryKey returns the primary key columns of a table, declared either on the table or on its columns
+func primaryKey(table Table) []string {
+	if len(table.PrimaryKey) > 0 {
+		return table.PrimaryKey
+	}
+	var keys []string
+	for _, column := range table.Columns {
+		if column.PrimaryKey {
+			keys = append(keys, column.Name)
+		}
+	}
+	return keys
+}
+
+func sameColumn(a, b Column) bool {
+	return strings.EqualFold(a.Type, b.Type) &&
+		a.NotNull == b.NotNull &&
+		a.Unique == b.Unique &&
+		normalizeSQL(a.Default) == normalizeSQL(b.Default) &&
+		strings.EqualFold(strings.ReplaceAll(a.References, " ", ""), strings.ReplaceAll(b.References, " ", "")) &&
+		sameAction(a.OnUpdate, b.OnUpdate) &&
+		sameAction(a.OnDelete, b.OnDelete)
+}
+
+// constantDefault reports whether a column default is a constant ADD COLUMN accepts, rather than an expression
+// in parentheses or the current time
+func constantDefault(value string) bool {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "(") {
+		return false
+	}
+	switch strings.ToUpper(value) {
+	case "CURRENT_TIME", "CURRENT_DATE", "CURRENT_TIMESTAMP":
+		return false
+	}
+	return true
+}
+
+// sameConstraints reports whether two lists of table constraints hold the same column lists, in any order
+func sameConstraints(a, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, columns := range a {
+		found := false
+		for _, other := range b {
+			found = found || sameNames(columns, other)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func sameAction(a, b string) bool {
+	if a == "" {
+		a = "NO ACTION"
+	}
+	if b == "" {
+		b = "NO ACTION"
+	}
+	return strings.EqualFold(a, b)
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(normalizeSQL(a[i]), normalizeSQL(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeSQL collapses whitespace and drops the trailing semicolon of a statement
+func normalizeSQL(sql string) string {
+	return strings.TrimSuffix(strings.Join(strings.Fields(sql), " "), ";")
+}
+
+func viewSQL(view View) string {
+	if view.Select != "" {
+		return CreateView(view.Name, view.Select).String()
+	}
+	return view.SQL
+}
+
+func createView(view View) *Query {
+	if view.Select != "" {
+		return CreateView(view.Name, view.Select)
+	}
+	return getQuery().Raw(normalizeSQL(view.SQL) + ";")
+}
+
+func triggerSQL(trigger Trigger) string {
+	if trigger.Actions != "" {
+		return CreateTrigger(trigger.Name, trigger.Table, trigger.When, trigger.Event, trigger.Actions).String()
+	}
+	return trigger.SQL
+}
+
+func createTrigger(trigger Trigger) *Query {
+	if trigger.Actions != "" {
+		return CreateTrigger(trigger.Name, trigger.Table, trigger.When, trigger.Event, trigger.Actions)
+	}
+	return getQuery().Raw(normalizeSQL(trigger.SQL) + ";")
+}
@@ -0,0 +1,258 @@
+package query_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+func diffStrings(queries []*query.Query) []string {
+	var statements []string
+	for _, q := range queries {
+		statements = append(statements, q.String())
+	}
+	return statements
+}
+
+func TestCreateTableFrom(t *testing.T) {
+	q := query.CreateTableFrom(query.Table{
+		Name: "members",
+		Columns: []query.Column{
+			{Name: "team_id", Type: "INTEGER", NotNull: true, PrimaryKey: true},
+			{Name: "user_id", Type: "INTEGER", NotNull: true, PrimaryKey: true},
+		},
+		PrimaryKey: []string{"team_id", "user_id"},
+		Unique:     [][]string{{"user_id", "team_id"}},
+		ForeignKeys: []query.ForeignKey{
+			{Table: "teams", From: []string{"team_id", "user_id"}, To: []string{"id", "owner_id"}, OnDelete: "CASCADE"},
+		},
+		Indexes: []query.Index{
+			{Name: "members_user", Columns: []string{"user_id"}},
+		},
+		WithoutRowID: true,
+		Strict:       true,
+	}).String()
+	expected := "CREATE TABLE members (team_id INTEGER NOT NULL, user_id INTEGER NOT NULL, PRIMARY KEY (team_id, user_id), UNIQUE (user_id, team_id), FOREIGN KEY (team_id, user_id) REFERENCES teams (id, owner_id) ON DELETE CASCADE) WITHOUT ROWID, STRICT;CREATE INDEX members_user ON members (user_id);"
+	if q != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, q)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	live := &query.Schema{
+		Tables: []query.Table{
+			{
+				Name: "users",
+				Columns: []query.Column{
+					{Name: "id", Type: "INTEGER", PrimaryKey: true},
+					{Name: "name", Type: "TEXT"},
+					{Name: "legacy", Type: "TEXT"},
+				},
+				Indexes: []query.Index{
+					{Name: "users_name", Columns: []string{"name"}},
+					{Name: "users_legacy", Columns: []string{"legacy"}},
+				},
+			},
+		},
+		Views: []query.View{{Name: "names", SQL: "CREATE VIEW names AS SELECT name FROM users"}},
+	}
+	desired := &query.Schema{
+		Tables: []query.Table{
+			{
+				Name: "users",
+				Columns: []query.Column{
+					{Name: "id", Type: "INTEGER", PrimaryKey: true},
+					{Name: "name", Type: "TEXT"},
+					{Name: "email", Type: "TEXT"},
+				},
+				Indexes: []query.Index{
+					{Name: "users_name", Columns: []string{"name"}, Unique: true},
+				},
+			},
+			{
+				Name:    "teams",
+				Columns: []query.Column{{Name: "id", Type: "INTEGER", PrimaryKey: true}},
+			},
+		},
+		Views: []query.View{{Name: "names", Select: "SELECT name FROM users"}},
+	}
+
+	_, err := query.Diff(desired, live, query.DiffOptions{})
+	var destructive *query.DestructiveError
+	if !errors.As(err, &destructive) {
+		t.Fatalf("Expected a destructive error, but got %v", err)
+	}
+	if !reflect.DeepEqual(destructive.Changes, []string{"drop column users.legacy"}) {
+		t.Errorf("Expected destructive changes [drop column users.legacy], but got %v", destructive.Changes)
+	}
+
+	m, err := query.Diff(desired, live, query.DiffOptions{AllowDestructive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Before) != 0 || len(m.After) != 0 {
+		t.Errorf("Expected no statements around the transaction, but got %q and %q", diffStrings(m.Before), diffStrings(m.After))
+	}
+	expected := []string{
+		"DROP INDEX users_name;",
+		"DROP INDEX users_legacy;",
+		"CREATE TABLE teams (id INTEGER PRIMARY KEY);",
+		"ALTER TABLE users ADD COLUMN email TEXT;",
+		"ALTER TABLE users DROP COLUMN legacy;",
+		"CREATE UNIQUE INDEX users_name ON users (name);",
+	}
+	if statements := diffStrings(m.Statements); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Expected statements %q, but got %q", expected, statements)
+	}
+
+	drift := query.Drift(desired, desired)
+	if len(drift) != 0 {
+		t.Errorf("Expected no drift, but got %v", drift)
+	}
+}
+
+func TestDiffRebuild(t *testing.T) {
+	live := &query.Schema{
+		Tables: []query.Table{
+			{
+				Name: "users",
+				Columns: []query.Column{
+					{Name: "id", Type: "INTEGER", PrimaryKey: true},
+					{Name: "age", Type: "TEXT"},
+				},
+			},
+			{Name: "old", Columns: []query.Column{{Name: "id", Type: "INTEGER"}}},
+		},
+		Triggers: []query.Trigger{{Name: "users_ai", Table: "users", SQL: "CREATE TRIGGER users_ai AFTER INSERT ON users BEGIN SELECT 1; END"}},
+	}
+	desired := &query.Schema{
+		Tables: []query.Table{
+			{
+				Name: "users",
+				Columns: []query.Column{
+					{Name: "id", Type: "INTEGER", PrimaryKey: true},
+					{Name: "age", Type: "INTEGER", NotNull: true, Default: "0"},
+				},
+				Indexes: []query.Index{{Name: "users_age", Columns: []string{"age"}}},
+			},
+		},
+		Triggers: []query.Trigger{{Name: "users_ai", Table: "users", When: "AFTER", Event: "INSERT", Actions: "BEGIN SELECT 1; END"}},
+	}
+
+	drift := query.Drift(desired, live)
+	if !reflect.DeepEqual(drift, []string{"rebuild table users", "drop table old"}) {
+		t.Errorf("Expected drift [rebuild table users drop table old], but got %v", drift)
+	}
+
+	m, err := query.Diff(desired, live, query.DiffOptions{AllowDestructive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before := diffStrings(m.Before); !reflect.DeepEqual(before, []string{"PRAGMA foreign_keys = OFF;"}) {
+		t.Errorf("Expected foreign keys disabled before the transaction, but got %q", before)
+	}
+	if after := diffStrings(m.After); !reflect.DeepEqual(after, []string{"PRAGMA foreign_keys = ON;"}) {
+		t.Errorf("Expected foreign keys enabled after the transaction, but got %q", after)
+	}
+	expected := []string{
+		"CREATE TABLE users_new (id INTEGER PRIMARY KEY, age INTEGER NOT NULL DEFAULT 0);",
+		"INSERT INTO users_new (id, age) SELECT id, age FROM users;",
+		"DROP TABLE users;",
+		"ALTER TABLE users_new RENAME TO users;",
+		"CREATE INDEX users_age ON users (age);",
+		"DROP TABLE old;",
+		"CREATE TRIGGER users_ai AFTER INSERT ON users BEGIN SELECT 1; END;",
+		"PRAGMA foreign_key_check;",
+	}
+	if statements := diffStrings(m.Statements); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Expected statements %q, but got %q", expected, statements)
+	}
+}
+
+func TestDiffRebuildNotNull(t *testing.T) {
+	live := &query.Schema{
+		Tables: []query.Table{{Name: "users", Columns: []query.Column{{Name: "id", Type: "INTEGER", PrimaryKey: true}}}},
+	}
+	desired := &query.Schema{
+		Tables: []query.Table{
+			{
+				Name: "users",
+				Columns: []query.Column{
+					{Name: "id", Type: "INTEGER", PrimaryKey: true},
+					{Name: "email", Type: "TEXT", NotNull: true, Unique: true},
+				},
+			},
+		},
+	}
+
+	if _, err := query.Diff(desired, live, query.DiffOptions{AllowDestructive: true}); err == nil {
+		t.Error("Expected an error for a NOT NULL column without a default, but got nil")
+	}
+
+	desired.Tables[0].Columns[1].Default = "''"
+	if _, err := query.Diff(desired, live, query.DiffOptions{AllowDestructive: true}); err != nil {
+		t.Errorf("Expected no error for a NOT NULL column with a default, but got %v", err)
+	}
+}
+
+func TestDiffAddColumn(t *testing.T) {
+	live := &query.Schema{
+		Tables: []query.Table{
+			{
+				Name:    "posts",
+				Columns: []query.Column{{Name: "id", Type: "INTEGER", PrimaryKey: true}, {Name: "author_id", Type: "INTEGER"}, {Name: "title", Type: "TEXT"}},
+			},
+		},
+	}
+	tests := []struct {
+		column  query.Column
+		rebuild bool
+	}{
+		{query.Column{Name: "views", Type: "INTEGER", NotNull: true, Default: "0"}, false},
+		{query.Column{Name: "created_at", Type: "DATETIME", Default: "CURRENT_TIMESTAMP"}, true},
+		{query.Column{Name: "slug", Type: "TEXT", Default: "(lower(title))"}, true},
+		{query.Column{Name: "team_id", Type: "INTEGER", References: "teams(id)"}, false},
+		{query.Column{Name: "team_id", Type: "INTEGER", References: "teams(id)", Default: "1"}, true},
+	}
+	for _, test := range tests {
+		desired := &query.Schema{Tables: []query.Table{live.Tables[0]}}
+		desired.Tables[0].Columns = append(append([]query.Column(nil), live.Tables[0].Columns...), test.column)
+		drift := query.Drift(desired, live)
+		if rebuild := len(drift) == 1 && drift[0] == "rebuild table posts"; rebuild != test.rebuild {
+			t.Errorf("Expected rebuild %v for column %+v, but got %v", test.rebuild, test.column, drift)
+		}
+	}
+
+	desired := &query.Schema{Tables: []query.Table{live.Tables[0]}}
+	desired.Tables[0].Unique = [][]string{{"author_id", "title"}}
+	if drift := query.Drift(desired, live); !reflect.DeepEqual(drift, []string{"rebuild table posts"}) {
+		t.Errorf("Expected a new UNIQUE constraint to rebuild the table, but got %v", drift)
+	}
+}
+
+func TestDiffGolden(t *testing.T) {
+	desired := &query.Schema{
+		Tables: []query.Table{
+			{
+				Name: "users",
+				Columns: []query.Column{
+					{Name: "id", Type: "INTEGER", PrimaryKey: true},
+					{Name: "email", Type: "TEXT", NotNull: true, Unique: true},
+					{Name: "team_id", Type: "INTEGER", References: "teams(id)", OnDelete: "SET NULL"},
+				},
+				Indexes: []query.Index{{Name: "users_team", Columns: []string{"team_id"}}},
+			},
+			{Name: "teams", Columns: []query.Column{{Name: "id", Type: "INTEGER", PrimaryKey: true}, {Name: "name", Type: "TEXT"}}},
+		},
+		Views: []query.View{{Name: "team_sizes", Select: "SELECT team_id, count(*) AS size FROM users GROUP BY team_id"}},
+	}
+
+	m, err := query.Diff(desired, &query.Schema{}, query.DiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	querytest.Golden(t, "diff_create", m.Statements...)
+}
@@ -0,0 +1,30 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Executor is an interface implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Exec is a function that builds the query and executes it on the executor
+func Exec(ctx context.Context, db Executor, q *Query) (sql.Result, error) {
+	query, args, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
+// QueryRows is a function that builds the query, runs it on the executor and returns the resulting rows
+func QueryRows(ctx context.Context, db Executor, q *Query) (*sql.Rows, error) {
+	query, args, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, query, args...)
+}
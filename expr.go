@@ -0,0 +1,105 @@
+package query
+
+// Expr is a struct holding an SQL expression and the arguments bound to its placeholders
+type Expr struct {
+	SQL  string
+	Args []any
+	// err is recorded by the query the expression is added to
+	err error
+}
+
+// Err is a function that returns the error found while building the expression
+func (e Expr) Err() error {
+	return e.err
+}
+
+// As is a function that returns the expression aliased as a column or table name
+func (e Expr) As(alias string) Expr {
+	return Expr{SQL: e.SQL + " AS " + alias, Args: e.Args, err: e.err}
+}
+
+// Asc is a function that returns the expression sorted in ascending order, for OrderByExpr
+func (e Expr) Asc() Expr {
+	return Expr{SQL: e.SQL + " ASC", Args: e.Args, err: e.err}
+}
+
+// Desc is a function that returns the expression sorted in descending order, for OrderByExpr
+func (e Expr) Desc() Expr {
+	return Expr{SQL: e.SQL + " DESC", Args: e.Args, err: e.err}
+}
+
+// Compare is a function that returns the comparison of the expression with a value bound as an argument,
+// where op is an SQL comparison operator such as =, <> or LIKE
+func (e Expr) Compare(op string, value any) Expr {
+	args := append(append([]any(nil), e.Args...), value)
+	return Expr{SQL: e.SQL + " " + op + " ?", Args: args, err: e.err}
+}
+
+// Eq is a function that returns the equality of the expression with a value bound as an argument
+func (e Expr) Eq(value any) Expr {
+	return e.Compare("=", value)
+}
+
+// SelectExpr is a function to start building a SELECT query statement of expressions
+func SelectExpr(exprs ...Expr) *Query {
+	return getQuery().SelectExpr(exprs...)
+}
+
+// SelectExpr is a function that returns a SELECT query for the specified expressions
+func (q *Query) SelectExpr(exprs ...Expr) *Query {
+	q.query = append(q.query, "SELECT "...)
+	q.exprs(exprs)
+	return q
+}
+
+// FromExpr is a function that returns a FROM clause for the specified expressions, such as table-valued functions
+func (q *Query) FromExpr(sources ...Expr) *Query {
+	q.query = append(q.query, " FROM "...)
+	q.exprs(sources)
+	return q
+}
+
+// JoinExpr is a function that returns a JOIN clause for the specified expression, such as a table-valued
+// function, with an ON condition unless it is empty
+func (q *Query) JoinExpr(source Expr, condition string) *Query {
+	q.query = append(q.query, " JOIN "...)
+	q.expr(source)
+	if condition != "" {
+		q.query = append(q.query, " ON "...)
+		q.query = append(q.query, condition...)
+	}
+	return q
+}
+
+// WhereExpr is a function that returns a WHERE clause for the specified expression
+func (q *Query) WhereExpr(expr Expr) *Query {
+	q.query = append(q.query, " WHERE "...)
+	q.expr(expr)
+	return q
+}
+
+// OrderByExpr is a function that returns an ORDER BY clause for the specified expressions
+func (q *Query) OrderByExpr(exprs ...Expr) *Query {
+	q.query = append(q.query, " ORDER BY "...)
+	q.exprs(exprs)
+	return q
+}
+
+// expr appends an expression and its arguments, recording its error
+func (q *Query) expr(expr Expr) {
+	if expr.err != nil {
+		q.setErr(expr.err)
+	}
+	q.query = append(q.query, expr.SQL...)
+	q.args = append(q.args, expr.Args...)
+}
+
+// exprs appends a comma-separated list of expressions and their arguments
+func (q *Query) exprs(exprs []Expr) {
+	for i, expr := range exprs {
+		if i > 0 {
+			q.query = append(q.query, ", "...)
+		}
+		q.expr(expr)
+	}
+}
@@ -0,0 +1,442 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidFilter is the error wrapped by the errors of FilterSpec.Parse, for a client error response
+var ErrInvalidFilter = errors.New("query: invalid filter")
+
+// FieldType is the type the values of a filter field are parsed as
+type FieldType int
+
+const (
+	FieldString FieldType = iota
+	FieldInt
+	FieldFloat
+	FieldBool
+	// FieldTime parses RFC 3339 times and 2006-01-02 dates
+	FieldTime
+)
+
+// Operator is a comparison a filter field permits, written as a suffix of the parameter such as age_gt
+type Operator string
+
+const (
+	OpEq  Operator = "eq"
+	OpNe  Operator = "ne"
+	OpGt  Operator = "gt"
+	OpGte Operator = "gte"
+	OpLt  Operator = "lt"
+	OpLte Operator = "lte"
+	// OpLike matches the values containing the parameter
+	OpLike Operator = "like"
+	// OpIn matches a comma-separated list of values
+	OpIn Operator = "in"
+	// OpNull matches NULL values when the parameter is true and the others when it is false
+	OpNull Operator = "null"
+)
+
+// operatorSQL holds the SQL of the operators comparing a column with a single value
+var operatorSQL = map[Operator]string{
+	OpEq: " = ?", OpNe: " <> ?", OpGt: " > ?", OpGte: " >= ?", OpLt: " < ?", OpLte: " <= ?",
+	OpLike: ` LIKE ? ESCAPE '\'`,
+}
+
+// FilterField is a struct declaring a field clients may filter or sort by
+type FilterField struct {
+	// Column is the SQL expression of the field, the name of the field when empty
+	Column string
+	Type   FieldType
+	// Operators are the operators the field permits, OpEq when empty
+	Operators []Operator
+	Sortable  bool
+}
+
+// FilterSpec is a struct declaring the fields clients may filter and sort by, used to parse untrusted
+// parameters such as ?status=active&age_gt=30&sort=-created_at into a Filter
+type FilterSpec struct {
+	Fields map[string]FilterField
+	// SortParam is the parameter holding the comma-separated sort fields, prefixed with - for a descending
+	// order, sort when empty
+	SortParam string
+	// DefaultSort is the order used when the parameters do not sort
+	DefaultSort []SortKey
+	// Reserved are the parameters that are ignored rather than rejected, such as cursor or limit
+	Reserved []string
+	// MaxValues is the largest number of times a parameter is repeated and of values of an OpIn parameter,
+	// 100 when zero
+	MaxValues int
+}
+
+// Condition is a struct representing a condition tree: a comparison of a column with values when Op is set,
+// otherwise the conjunction of All or the disjunction of Any
+type Condition struct {
+	Column string
+	Op     Operator
+	Values []any
+	All    []Condition
+	Any    []Condition
+}
+
+// Filter is a struct holding the condition and order parsed from untrusted parameters
+type Filter struct {
+	Where Condition
+	Sort  []SortKey
+}
+
+// Parse is a function that parses URL query parameters into a Filter, rejecting unknown fields, operators the
+// fields do not permit, unsortable fields and values not of the type of their field with an error wrapping
+// ErrInvalidFilter
+func (s FilterSpec) Parse(values url.Values) (Filter, error) {
+	var filter Filter
+	sortParam := s.SortParam
+	if sortParam == "" {
+		sortParam = "sort"
+	}
+
+	// parameters are parsed in order so that the conditions and errors do not depend on map iteration
+	params := make([]string, 0, len(values))
+	for param := range values {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		if len(values[param]) > s.maxValues() {
+			return Filter{}, fmt.Errorf("%w: %s is repeated more than %d times", ErrInvalidFilter, param, s.maxValues())
+		}
+		switch {
+		case param == sortParam:
+			keys, err := s.parseSort(values[param])
+			if err != nil {
+				return Filter{}, err
+			}
+			filter.Sort = keys
+			continue
+		case containsString(s.Reserved, param):
+			continue
+		}
+
+		name, field, op, err := s.field(param)
+		if err != nil {
+			return Filter{}, err
+		}
+		for _, value := range values[param] {
+			condition, err := s.condition(name, field, op, value)
+			if err != nil {
+				return Filter{}, err
+			}
+			filter.Where.All = append(filter.Where.All, condition)
+		}
+	}
+	if filter.Sort == nil {
+		filter.Sort = s.DefaultSort
+	}
+	return filter, nil
+}
+
+// ParseMap is a function that parses parameters decoded from a map, such as a JSON request body, into a
+// Filter like Parse. Slices hold the values of repeated parameters, which must be strings, numbers or booleans.
+func (s FilterSpec) ParseMap(m map[string]any) (Filter, error) {
+	values := url.Values{}
+	for param, value := range m {
+		switch v := value.(type) {
+		case []any:
+			for _, element := range v {
+				text, ok := scalarString(element)
+				if !ok {
+					return Filter{}, fmt.Errorf("%w: %s takes strings, numbers or booleans, not %T", ErrInvalidFilter, param, element)
+				}
+				values.Add(param, text)
+			}
+		case []string:
+			values[param] = append(values[param], v...)
+		default:
+			text, ok := scalarString(v)
+			if !ok {
+				return Filter{}, fmt.Errorf("%w: %s takes strings, numbers or booleans, not %T", ErrInvalidFilter, param, v)
+			}
+			values.Add(param, text)
+		}
+	}
+	return s.Parse(values)
+}
+
+// scalarString formats a string, number or boolean decoded from a map as a parameter value
+func scalarString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return fmt.Sprint(value), true
+	}
+	return "", false
+}
+
+// maxValues returns the largest number of values of a parameter
+func (s FilterSpec) maxValues() int {
+	if s.MaxValues <= 0 {
+		return 100
+	}
+	return s.MaxValues
+}
+
+// field returns the field and the operator of a parameter, which is the name of a field optionally suffixed
+// with _ and an operator
+func (s FilterSpec) field(param string) (string, FilterField, Operator, error) {
+	name, op := param, OpEq
+	field, ok := s.Fields[name]
+	if !ok {
+		if i := strings.LastIndexByte(param, '_'); i > 0 {
+			name, op = param[:i], Operator(param[i+1:])
+			field, ok = s.Fields[name]
+		}
+	}
+	if !ok {
+		return "", FilterField{}, "", fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, param)
+	}
+
+	permitted := field.Operators
+	if len(permitted) == 0 {
+		permitted = []Operator{OpEq}
+	}
+	for _, p := range permitted {
+		if p == op {
+			return name, field, op, nil
+		}
+	}
+	return "", FilterField{}, "", fmt.Errorf("%w: operator %s is not permitted on field %q", ErrInvalidFilter, op, name)
+}
+
+// condition returns the comparison of a field with a parameter value
+func (s FilterSpec) condition(name string, field FilterField, op Operator, value string) (Condition, error) {
+	column := field.Column
+	if column == "" {
+		column = name
+	}
+	condition := Condition{Column: column, Op: op}
+	switch op {
+	case OpNull:
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return Condition{}, fmt.Errorf("%w: %s_null takes true or false, not %q", ErrInvalidFilter, name, value)
+		}
+		condition.Values = []any{isNull}
+	case OpIn:
+		elements := strings.Split(value, ",")
+		if len(elements) > s.maxValues() {
+			return Condition{}, fmt.Errorf("%w: %s_in takes at most %d values", ErrInvalidFilter, name, s.maxValues())
+		}
+		for _, element := range elements {
+			v, err := parseField(field.Type, element)
+			if err != nil {
+				return Condition{}, fmt.Errorf("%w: %s: %v", ErrInvalidFilter, name, err)
+			}
+			condition.Values = append(condition.Values, v)
+		}
+	case OpLike:
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+		condition.Values = []any{"%" + escaped + "%"}
+	default:
+		v, err := parseField(field.Type, value)
+		if err != nil {
+			return Condition{}, fmt.Errorf("%w: %s: %v", ErrInvalidFilter, name, err)
+		}
+		condition.Values = []any{v}
+	}
+	return condition, nil
+}
+
+// parseField parses a parameter value as the type of its field
+func parseField(typ FieldType, value string) (any, error) {
+	switch typ {
+	case FieldInt:
+		return strconv.ParseInt(value, 10, 64)
+	case FieldFloat:
+		return strconv.ParseFloat(value, 64)
+	case FieldBool:
+		return strconv.ParseBool(value)
+	case FieldTime:
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02", value)
+	}
+	return value, nil
+}
+
+// parseSort parses the sort fields of the sort parameters
+func (s FilterSpec) parseSort(values []string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if name == "" {
+				continue
+			}
+			key := SortKey{}
+			switch {
+			case strings.HasPrefix(name, "-"):
+				name, key.Desc = name[1:], true
+			case strings.HasPrefix(name, "+"):
+				name = name[1:]
+			}
+			field, ok := s.Fields[name]
+			if !ok || !field.Sortable {
+				return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidFilter, name)
+			}
+			key.Column = field.Column
+			if key.Column == "" {
+				key.Column = name
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, element := range list {
+		if element == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Empty is a function that reports whether the condition matches every row
+func (c Condition) Empty() bool {
+	if c.Op != "" {
+		return false
+	}
+	for _, condition := range c.All {
+		if !condition.Empty() {
+			return false
+		}
+	}
+	for _, condition := range c.Any {
+		if !condition.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Expr is a function that returns the condition as an SQL expression with its values bound as arguments
+func (c Condition) Expr() Expr {
+	var e Expr
+	c.appendTo(&e, false)
+	if e.SQL == "" {
+		e.SQL = "1"
+	}
+	return e
+}
+
+// appendTo appends the condition to an expression, in parentheses when it is a disjunction nested in a
+// conjunction
+func (c Condition) appendTo(e *Expr, nested bool) {
+	if c.Op != "" {
+		if c.Op == OpIn && len(c.Values) == 0 {
+			// no value is in the empty list
+			e.SQL += "0"
+			return
+		}
+		if c.Op != OpIn && len(c.Values) != 1 {
+			if e.err == nil {
+				e.err = fmt.Errorf("%w: operator %s on %s takes 1 value, not %d", ErrInvalidFilter, c.Op, c.Column, len(c.Values))
+			}
+			e.SQL += "0"
+			return
+		}
+		e.SQL += c.Column
+		switch c.Op {
+		case OpNull:
+			if isNull, _ := c.Values[0].(bool); isNull {
+				e.SQL += " IS NULL"
+			} else {
+				e.SQL += " IS NOT NULL"
+			}
+		case OpIn:
+			e.SQL += " IN (" + strings.Repeat(", ?", len(c.Values))[2:] + ")"
+			e.Args = append(e.Args, c.Values...)
+		default:
+			sql, ok := operatorSQL[c.Op]
+			if !ok {
+				if e.err == nil {
+					e.err = fmt.Errorf("%w: unknown operator %s", ErrInvalidFilter, c.Op)
+				}
+				sql = " = ?"
+			}
+			e.SQL += sql
+			e.Args = append(e.Args, c.Values[0])
+		}
+		return
+	}
+
+	sep, conditions := " AND ", c.All
+	if len(c.All) == 0 {
+		sep, conditions = " OR ", c.Any
+	}
+	var parts []Condition
+	for _, condition := range conditions {
+		if !condition.Empty() {
+			parts = append(parts, condition)
+		}
+	}
+	grouped := len(parts) > 1 && (nested || sep == " OR ")
+	if grouped {
+		e.SQL += "("
+	}
+	for i, condition := range parts {
+		if i > 0 {
+			e.SQL += sep
+		}
+		condition.appendTo(e, true)
+	}
+	if grouped {
+		e.SQL += ")"
+	}
+}
+
+// Filter is a function that adds the condition of a filter to the WHERE clause of the query, before the
+// clauses following it such as ORDER BY, without ordering it: OrderByKeys or Page sort the query by the keys of
+// the filter
+func (q *Query) Filter(filter Filter) *Query {
+	if filter.Where.Empty() {
+		return q
+	}
+	q.andWhere(func() { q.expr(filter.Where.Expr()) })
+	return q
+}
+
+// OrderByKeys is a function that returns an ORDER BY clause for the specified sort keys
+func (q *Query) OrderByKeys(keys ...SortKey) *Query {
+	if len(keys) == 0 {
+		return q
+	}
+	q.query = append(q.query, " ORDER BY "...)
+	for i, key := range keys {
+		if i > 0 {
+			q.query = append(q.query, ", "...)
+		}
+		q.query = append(q.query, key.Column...)
+		if key.Desc {
+			q.query = append(q.query, " DESC"...)
+		} else {
+			q.query = append(q.query, " ASC"...)
+		}
+	}
+	return q
+}
@@ -0,0 +1,238 @@
+package query_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/tinytoolkit/query"
+)
+
+var usersFilter = query.FilterSpec{
+	Fields: map[string]query.FilterField{
+		"status":     {Operators: []query.Operator{query.OpEq, query.OpIn}},
+		"age":        {Type: query.FieldInt, Operators: []query.Operator{query.OpEq, query.OpGt, query.OpLte}, Sortable: true},
+		"name":       {Column: "users.name", Operators: []query.Operator{query.OpLike}, Sortable: true},
+		"created_at": {Type: query.FieldTime, Operators: []query.Operator{query.OpGte}, Sortable: true},
+		"deleted_at": {Operators: []query.Operator{query.OpNull}},
+		"id":         {Type: query.FieldInt, Sortable: true},
+	},
+	DefaultSort: []query.SortKey{{Column: "id"}},
+	Reserved:    []string{"cursor"},
+}
+
+func TestFilterParse(t *testing.T) {
+	values, _ := url.ParseQuery("status=active&age_gt=30&name_like=50%25_o%27k&created_at_gte=2024-05-06" +
+		"&deleted_at_null=true&status_in=a,b&cursor=xyz&sort=-created_at,id")
+	filter, err := usersFilter.Parse(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, args, err := query.Select("*").From("users").Where("team_id = ?").Args(4).Filter(filter).OrderByKeys(filter.Sort...).Limit(10).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "SELECT * FROM users WHERE team_id = ? AND age > ? AND created_at >= ? AND deleted_at IS NULL AND " +
+		`users.name LIKE ? ESCAPE '\' AND status = ? AND status IN (?, ?) ORDER BY created_at DESC, id ASC LIMIT ?`
+	if q != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, q)
+	}
+	expectedArgs := []any{4, int64(30), "2024-05-06 00:00:00.000", `%50\%\_o'k%`, "active", "a", "b", 10}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected args %v, but got %v", expectedArgs, args)
+	}
+}
+
+func TestFilterClauses(t *testing.T) {
+	filter, err := usersFilter.ParseMap(map[string]any{"age": 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		q        *query.Query
+		expected string
+		args     []any
+	}{
+		{
+			query.Select("*").From("users").Where("team_id = ? OR public").Args(4).OrderBy("id").Limit(10),
+			"SELECT * FROM users WHERE (team_id = ? OR public) AND age = ? ORDER BY id LIMIT ?",
+			[]any{4, int64(42), 10},
+		},
+		{
+			query.Select("status", "count(*)").From("users").GroupBy("status").Having("count(*) > ?").Args(1),
+			"SELECT status, count(*) FROM users WHERE age = ? GROUP BY status HAVING count(*) > ?",
+			[]any{int64(42), 1},
+		},
+		{
+			query.DeleteFrom("users").Where("team_id = ?").Args(4).Returning("id"),
+			"DELETE FROM users WHERE team_id = ? AND age = ? RETURNING id",
+			[]any{4, int64(42)},
+		},
+	}
+	for _, test := range tests {
+		q, args, err := test.q.Filter(filter).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q != test.expected || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("Expected query '%s' %v, but got '%s' %v", test.expected, test.args, q, args)
+		}
+	}
+}
+
+func TestFilterSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	_, err = db.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []*query.Query{
+		query.InsertInto("users").Columns("name").Values("today"),
+		query.InsertInto("users").Columns("name", "created_at").Values("bound", time.Now()),
+		query.InsertInto("users").Columns("name", "created_at").Values("old", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)),
+	} {
+		if _, err := query.Exec(ctx, db, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the rows created today by CURRENT_TIMESTAMP or a bound time compare after the start of the day
+	values := url.Values{"created_at_gte": {time.Now().UTC().Format("2006-01-02")}}
+	filter, err := usersFilter.Parse(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := query.QueryRows(ctx, db, query.Select("name").From("users").Filter(filter).OrderBy("id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"today", "bound"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected users %q, but got %q", expected, names)
+	}
+}
+
+func TestFilterParseMap(t *testing.T) {
+	filter, err := usersFilter.ParseMap(map[string]any{"age": 42, "status": []any{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, args := query.Select("*").From("users").Filter(filter).OrderByKeys(filter.Sort...).Query()
+	if q != "SELECT * FROM users WHERE age = ? AND status = ? AND status = ? ORDER BY id ASC" || !reflect.DeepEqual(args, []any{int64(42), "a", "b"}) {
+		t.Errorf("Unexpected query '%s' %v", q, args)
+	}
+
+	for _, invalid := range []map[string]any{
+		{"age": nil},
+		{"status": map[string]any{"$ne": "a"}},
+		{"status": []any{"a", []any{"b"}}},
+	} {
+		if _, err := usersFilter.ParseMap(invalid); !errors.Is(err, query.ErrInvalidFilter) {
+			t.Errorf("Expected ErrInvalidFilter for %v, but got %v", invalid, err)
+		}
+	}
+}
+
+func TestFilterParseErrors(t *testing.T) {
+	for _, raw := range []string{
+		"password=x",
+		"status_gt=a",
+		"age=old",
+		"created_at_gte=yesterday",
+		"deleted_at_null=maybe",
+		"sort=status",
+		"sort=-password",
+		"age_gt=1%3BDROP%20TABLE%20users",
+	} {
+		values, _ := url.ParseQuery(raw)
+		if _, err := usersFilter.Parse(values); !errors.Is(err, query.ErrInvalidFilter) {
+			t.Errorf("Expected ErrInvalidFilter for '%s', but got %v", raw, err)
+		}
+	}
+
+	spec := usersFilter
+	spec.MaxValues = 2
+	if _, err := spec.Parse(url.Values{"status_in": {"a,b,c"}}); !errors.Is(err, query.ErrInvalidFilter) {
+		t.Errorf("Expected ErrInvalidFilter for too many values, but got %v", err)
+	}
+	if _, err := spec.Parse(url.Values{"status": {"a", "b", "c"}}); !errors.Is(err, query.ErrInvalidFilter) {
+		t.Errorf("Expected ErrInvalidFilter for a parameter repeated too many times, but got %v", err)
+	}
+}
+
+func TestConditionTree(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	condition := query.Condition{All: []query.Condition{
+		{Column: "active", Op: query.OpEq, Values: []any{true}},
+		{Any: []query.Condition{
+			{Column: "role", Op: query.OpIn, Values: []any{"admin", "owner"}},
+			{All: []query.Condition{
+				{Column: "created_at", Op: query.OpGte, Values: []any{since}},
+				{Column: "invited_by", Op: query.OpNull, Values: []any{false}},
+			}},
+		}},
+		{Any: []query.Condition{}},
+	}}
+
+	expr := condition.Expr()
+	expected := "active = ? AND (role IN (?, ?) OR (created_at >= ? AND invited_by IS NOT NULL))"
+	if expr.SQL != expected || len(expr.Args) != 4 {
+		t.Errorf("Expected condition '%s', but got '%s' %v", expected, expr.SQL, expr.Args)
+	}
+	if s := (query.Condition{}).Expr().SQL; s != "1" {
+		t.Errorf("Expected the empty condition to be '1', but got '%s'", s)
+	}
+
+	if q := query.Select("*").From("users").Filter(query.Filter{}).String(); q != "SELECT * FROM users" {
+		t.Errorf("Expected an empty filter to add no WHERE clause, but got '%s'", q)
+	}
+
+	empty := query.Condition{Any: []query.Condition{
+		{Column: "role", Op: query.OpIn},
+		{Column: "owner", Op: query.OpEq, Values: []any{true}},
+	}}
+	if expr := empty.Expr(); expr.SQL != "(0 OR owner = ?)" || expr.Err() != nil {
+		t.Errorf("Expected an empty IN to match nothing, but got '%s' (%v)", expr.SQL, expr.Err())
+	}
+	for _, invalid := range []query.Condition{
+		{Column: "age", Op: query.OpEq},
+		{Column: "age", Op: query.OpGt, Values: []any{1, 2}},
+		{Column: "deleted_at", Op: query.OpNull},
+	} {
+		if err := invalid.Expr().Err(); !errors.Is(err, query.ErrInvalidFilter) {
+			t.Errorf("Expected ErrInvalidFilter for %v, but got %v", invalid, err)
+		}
+	}
+
+	filter := query.Filter{Where: query.Condition{Column: "team_id", Op: query.OpEq, Values: []any{7}}}
+	q := query.Select("*").From("users").Where("owner OR public").Filter(filter).String()
+	if expected := "SELECT * FROM users WHERE (owner OR public) AND team_id = ?"; q != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, q)
+	}
+}
@@ -0,0 +1,151 @@
+package query
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// QueryFingerprint is a struct holding the normalized text of a query and its hash, identical for queries
+// of the same shape whatever their literals, parameters, IN list lengths, whitespace and comments
+type QueryFingerprint struct {
+	Text string
+	Hash uint64
+}
+
+// String is a function that returns the hash of the fingerprint as 16 hexadecimal digits
+func (f QueryFingerprint) String() string {
+	s := strconv.FormatUint(f.Hash, 16)
+	return strings.Repeat("0", 16-len(s)) + s
+}
+
+// Fingerprint is a function that returns the fingerprint of the query without resetting it
+func (q *Query) Fingerprint() QueryFingerprint {
+	return Fingerprint(string(q.query))
+}
+
+// Fingerprint is a function that normalizes an SQL string and returns its fingerprint: comments are dropped,
+// literals and parameters replaced with ?, keywords upper-cased and other unquoted names lower-cased, IN
+// lists of values and repeated VALUES rows collapsed, and whitespace and trailing semicolons normalized
+func Fingerprint(sql string) QueryFingerprint {
+	var words []string
+	for _, tok := range tokenize(sql) {
+		switch tok.kind {
+		case tokenSpace, tokenComment:
+			continue
+		case tokenString, tokenBlob, tokenNumber, tokenParam:
+			words = append(words, "?")
+		case tokenWord:
+			if upper := strings.ToUpper(tok.text); keywords[upper] || upper == "TRUE" || upper == "FALSE" {
+				words = append(words, upper)
+			} else {
+				words = append(words, strings.ToLower(tok.text))
+			}
+		default:
+			words = append(words, tok.text)
+		}
+	}
+	for len(words) > 0 && words[len(words)-1] == ";" {
+		words = words[:len(words)-1]
+	}
+	words = collapseLists(words)
+
+	var sb strings.Builder
+	for i, word := range words {
+		if i > 0 && spaced(words[i-1], word) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(word)
+	}
+	text := sb.String()
+
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return QueryFingerprint{Text: text, Hash: h.Sum64()}
+}
+
+// collapseLists replaces IN lists of values with IN (...) and the rows of VALUES repeating the first with ...
+func collapseLists(words []string) []string {
+	var out []string
+	for i := 0; i < len(words); i++ {
+		out = append(out, words[i])
+		switch words[i] {
+		case "IN":
+			if end := valueList(words, i+1); end > 0 {
+				out = append(out, "(", "...", ")")
+				i = end
+			}
+		case "VALUES":
+			end := tuple(words, i+1)
+			if end < 0 {
+				continue
+			}
+			row := strings.Join(words[i+1:end+1], " ")
+			out = append(out, words[i+1:end+1]...)
+			i = end
+			repeated := false
+			for i+1 < len(words) && words[i+1] == "," {
+				next := tuple(words, i+2)
+				if next < 0 || strings.Join(words[i+2:next+1], " ") != row {
+					break
+				}
+				i, repeated = next, true
+			}
+			if repeated {
+				out = append(out, ",", "...")
+			}
+		}
+	}
+	return out
+}
+
+// valueList returns the index of the parenthesis closing the list of values starting at i, or -1
+func valueList(words []string, i int) int {
+	if i >= len(words) || words[i] != "(" {
+		return -1
+	}
+	for j := i + 1; j < len(words); j += 2 {
+		if words[j] != "?" && words[j] != "NULL" && words[j] != "TRUE" && words[j] != "FALSE" {
+			return -1
+		}
+		if j+1 < len(words) && words[j+1] == ")" {
+			return j + 1
+		}
+		if j+1 >= len(words) || words[j+1] != "," {
+			return -1
+		}
+	}
+	return -1
+}
+
+// tuple returns the index of the parenthesis closing the parenthesized expression starting at i, or -1
+func tuple(words []string, i int) int {
+	if i >= len(words) || words[i] != "(" {
+		return -1
+	}
+	depth := 0
+	for j := i; j < len(words); j++ {
+		switch words[j] {
+		case "(":
+			depth++
+		case ")":
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// spaced reports whether a space separates two normalized words
+func spaced(prev, next string) bool {
+	switch {
+	case next == "," || next == ")" || next == "." || next == ";" || prev == "(" || prev == ".":
+		return false
+	case next == "(":
+		// function calls and table definitions keep the parenthesis next to the name
+		last := prev[len(prev)-1]
+		return keywords[prev] || !isWordByte(last) && last != '"' && last != '`' && last != ']'
+	}
+	return true
+}
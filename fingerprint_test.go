@@ -0,0 +1,69 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		queries  []string
+		expected string
+	}{
+		{
+			[]string{
+				query.Select("id").From("users").Where("").In("id", 1, 2, 3).String(),
+				"select ID from Users where id in (?)",
+				"SELECT id -- the id\nFROM users WHERE id IN ( 4, 'five', NULL );",
+			},
+			"SELECT id FROM users WHERE id IN (...)",
+		},
+		{
+			[]string{
+				"INSERT INTO foo (a, b) VALUES (?, ?), (?, ?), (?, ?) RETURNING id",
+				"insert into foo(a,b) values(1,'x'),(2,'y') returning id",
+			},
+			"INSERT INTO foo(a, b) VALUES (?, ?), ... RETURNING id",
+		},
+		{
+			[]string{
+				"SELECT count(*), \"Name\" FROM t WHERE x = :x AND y > ?2 LIMIT 10 OFFSET 20",
+				"SELECT COUNT( * ) , \"Name\" FROM t WHERE x = 'a' AND y > X'00' LIMIT ? OFFSET ?",
+			},
+			`SELECT count(*), "Name" FROM t WHERE x = ? AND y > ? LIMIT ? OFFSET ?`,
+		},
+		{
+			[]string{"SELECT a FROM t WHERE a IN (SELECT b FROM u)"},
+			"SELECT a FROM t WHERE a IN (SELECT b FROM u)",
+		},
+	}
+	for _, test := range tests {
+		first := query.Fingerprint(test.queries[0])
+		if first.Text != test.expected {
+			t.Errorf("Expected fingerprint '%s', but got '%s'", test.expected, first.Text)
+		}
+		for _, q := range test.queries[1:] {
+			if f := query.Fingerprint(q); f != first {
+				t.Errorf("Expected fingerprint %v of '%s', but got %v '%s'", first, first.Text, f, f.Text)
+			}
+		}
+	}
+
+	if a, b := query.Fingerprint("SELECT a FROM t"), query.Fingerprint("SELECT b FROM t"); a.Hash == b.Hash {
+		t.Errorf("Expected different hashes for different queries")
+	}
+	if s := query.Fingerprint("SELECT 1").String(); len(s) != 16 {
+		t.Errorf("Expected 16 hexadecimal digits, but got '%s'", s)
+	}
+}
+
+func TestQueryFingerprint(t *testing.T) {
+	q := query.Select("*").From("foo").Where("id = ?").Args(1)
+	if f := q.Fingerprint(); f.Text != "SELECT * FROM foo WHERE id = ?" {
+		t.Errorf("Expected fingerprint 'SELECT * FROM foo WHERE id = ?', but got '%s'", f.Text)
+	}
+	if s := q.String(); s != "SELECT * FROM foo WHERE id = ?" {
+		t.Errorf("Expected the query to be kept, but got '%s'", s)
+	}
+}
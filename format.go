@@ -0,0 +1,434 @@
+package query
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// FormatOptions is a struct holding how Format lays out a query
+type FormatOptions struct {
+	// Indent is the indentation of nested lines, two spaces when empty
+	Indent string
+	// Width is the line length above which a select list is split into one column per line, 80 when zero
+	Width int
+	// LowerKeywords renders keywords in lower case instead of upper case
+	LowerKeywords bool
+}
+
+// Format is a function that returns the query string laid out for reading: one clause per line, AND and OR
+// conditions and subqueries indented, long select lists and table definitions split into one item per line.
+// The query is not reset.
+func (q *Query) Format(opts FormatOptions) string {
+	return Format(string(q.query), opts)
+}
+
+// Format is a function that lays out an SQL string like Query.Format, leaving literals, identifiers and
+// comments untouched
+func Format(sql string, opts FormatOptions) string {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	if opts.Width <= 0 {
+		opts.Width = 80
+	}
+
+	f := &formatter{opts: opts, frames: []*frame{{kind: frameBlock}}}
+	for _, tok := range tokenize(sql) {
+		if tok.kind == tokenSpace {
+			if len(f.tokens) > 0 {
+				f.tokens[len(f.tokens)-1].spaceAfter = true
+			}
+			continue
+		}
+		f.tokens = append(f.tokens, spacedToken{token: tok})
+	}
+	f.format()
+	return strings.TrimRight(string(f.buf), " \n")
+}
+
+// Script is a function that builds the queries and returns them formatted as an SQL script, such as a
+// migration file. Queries with arguments cannot be written to a script and return an error.
+func Script(opts FormatOptions, queries ...*Query) (string, error) {
+	var sb strings.Builder
+	for i, q := range queries {
+		query, args, err := q.Build()
+		if err != nil {
+			return "", err
+		}
+		if len(args) > 0 {
+			return "", fmt.Errorf("query: cannot write the arguments of %q to a script", query)
+		}
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		query = Format(query, opts)
+		sb.WriteString(query)
+		if !strings.HasSuffix(query, ";") {
+			sb.WriteString(";")
+		}
+	}
+	if sb.Len() > 0 {
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+type spacedToken struct {
+	token
+	spaceAfter bool
+}
+
+type frameKind int
+
+const (
+	// frameInline is a parenthesized expression kept on its line
+	frameInline frameKind = iota
+	// frameBlock is a statement or subquery laid out one clause per line
+	frameBlock
+	// frameList is a table definition laid out one item per line
+	frameList
+	// frameTrigger is the BEGIN ... END body of a trigger laid out one statement per line
+	frameTrigger
+)
+
+// frame is the layout state of a statement, subquery or parenthesized expression
+type frame struct {
+	kind    frameKind
+	indent  int
+	outer   int
+	clause  string
+	split   bool
+	between int
+	cases   int
+}
+
+type formatter struct {
+	opts      FormatOptions
+	tokens    []spacedToken
+	buf       []byte
+	frames    []*frame
+	lineStart bool
+	// create is set within a CREATE statement, table while its table definition is still to come
+	create bool
+	table  bool
+}
+
+// clauses are the keyword sequences starting a line in a statement, longest first
+var clauses = [][]string{
+	{"LEFT", "OUTER", "JOIN"}, {"RIGHT", "OUTER", "JOIN"}, {"FULL", "OUTER", "JOIN"},
+	{"LEFT", "JOIN"}, {"RIGHT", "JOIN"}, {"FULL", "JOIN"}, {"INNER", "JOIN"}, {"CROSS", "JOIN"},
+	{"NATURAL", "JOIN"}, {"JOIN"},
+	{"GROUP", "BY"}, {"ORDER", "BY"}, {"UNION", "ALL"}, {"UNION"}, {"INTERSECT"}, {"EXCEPT"},
+	{"ON", "CONFLICT"}, {"DELETE", "FROM"},
+	{"SELECT"}, {"FROM"}, {"WHERE"}, {"HAVING"}, {"WINDOW"}, {"LIMIT"}, {"VALUES"}, {"SET"}, {"RETURNING"},
+	{"INSERT"}, {"REPLACE"}, {"UPDATE"}, {"DELETE"},
+}
+
+// phrases are keyword sequences kept together on a line that would otherwise start a clause
+var phrases = [][]string{
+	{"IS", "NOT", "DISTINCT", "FROM"}, {"IS", "DISTINCT", "FROM"}, {"DEFAULT", "VALUES"},
+	{"OR", "REPLACE"}, {"ON", "UPDATE"}, {"ON", "DELETE"}, {"ON", "INSERT"},
+	{"SET", "NULL"}, {"SET", "DEFAULT"},
+}
+
+func (f *formatter) format() {
+	for i := 0; i < len(f.tokens); i++ {
+		tok := f.tokens[i]
+		fr := f.frames[len(f.frames)-1]
+		space := i > 0 && f.tokens[i-1].spaceAfter
+
+		switch {
+		case tok.kind == tokenComment:
+			f.write(tok.text, space)
+			if strings.HasPrefix(tok.text, "--") {
+				f.newline(f.lineIndent())
+			}
+
+		case tok.text == "(":
+			f.write("(", space)
+			next := f.word(i + 1)
+			switch {
+			case next == "SELECT" || next == "WITH" || next == "VALUES":
+				f.push(frameBlock)
+			case f.table && fr.kind == frameBlock:
+				f.table = false
+				f.push(frameList)
+			default:
+				f.push(frameInline)
+			}
+
+		case tok.text == ")":
+			if len(f.frames) > 1 {
+				f.frames = f.frames[:len(f.frames)-1]
+				if fr.kind != frameInline {
+					f.newline(fr.outer)
+				}
+			}
+			f.write(")", space)
+
+		case tok.text == ",":
+			f.write(",", false)
+			if fr.kind == frameList {
+				f.newline(fr.indent)
+			} else if fr.split && fr.clause == "SELECT" {
+				f.newline(fr.indent + 1)
+			}
+
+		case tok.text == ";":
+			f.write(";", false)
+			if fr.kind == frameTrigger {
+				f.newline(fr.indent)
+			} else if len(f.frames) == 1 {
+				*fr = frame{kind: frameBlock}
+				f.create, f.table = false, false
+				if i+1 < len(f.tokens) {
+					f.newline(0)
+					f.buf = append(f.buf, '\n')
+				}
+			}
+
+		case tok.kind == tokenWord:
+			i = f.keyword(i, fr, space)
+
+		default:
+			f.write(tok.text, space)
+		}
+	}
+}
+
+// keyword writes the word at i, laying out the clause it may start, and returns the index of its last word
+func (f *formatter) keyword(i int, fr *frame, space bool) int {
+	word := strings.ToUpper(f.tokens[i].text)
+	statement := len(f.buf) == 0 || f.lineStart && len(f.frames) == 1 && fr.clause == ""
+	if statement && word == "CREATE" {
+		f.create = true
+	}
+	if f.create && len(f.frames) == 1 {
+		switch word {
+		case "TABLE":
+			f.table = true
+		case "AS":
+			f.table = false
+		}
+	}
+
+	if fr.kind == frameBlock || fr.kind == frameTrigger {
+		if n := f.match(i, phrases); n > f.match(i, clauses) {
+			f.writeWords(i, n, space)
+			return i + n - 1
+		}
+		if n := f.match(i, clauses); n > 0 && f.startsClause(i, word) {
+			clause := f.words(i, n)
+			if !f.lineStart {
+				f.newline(fr.indent)
+			}
+			f.writeWords(i, n, space)
+			fr.clause, fr.split, fr.between = clause, false, 0
+			i += n - 1
+			if clause == "SELECT" {
+				if next := f.word(i + 1); next == "DISTINCT" || next == "ALL" {
+					i++
+					f.writeWords(i, 1, true)
+				}
+				fr.split = f.selectWidth(i+1) > f.opts.Width
+				if fr.split {
+					f.newline(fr.indent + 1)
+				}
+			}
+			return i
+		}
+	}
+
+	switch word {
+	case "BETWEEN":
+		fr.between++
+	case "CASE":
+		fr.cases++
+	case "AND", "OR":
+		if word == "AND" && fr.between > 0 {
+			fr.between--
+			break
+		}
+		switch fr.clause {
+		case "WHERE", "HAVING", "JOIN", "LEFT JOIN", "RIGHT JOIN", "FULL JOIN", "INNER JOIN", "CROSS JOIN",
+			"NATURAL JOIN", "LEFT OUTER JOIN", "RIGHT OUTER JOIN", "FULL OUTER JOIN":
+			f.newline(fr.indent + 1)
+		}
+	case "BEGIN":
+		if f.create && len(f.frames) == 1 {
+			f.writeWords(i, 1, space)
+			f.push(frameTrigger)
+			return i
+		}
+	case "END":
+		if fr.cases > 0 {
+			fr.cases--
+		} else if fr.kind == frameTrigger {
+			f.frames = f.frames[:len(f.frames)-1]
+			f.newline(fr.outer)
+		}
+	}
+	f.writeWords(i, 1, space)
+	return i
+}
+
+// startsClause reports whether a clause keyword starts a line rather than continuing an expression
+func (f *formatter) startsClause(i int, word string) bool {
+	switch word {
+	case "INSERT", "REPLACE", "UPDATE", "DELETE":
+		// statements start a line after a common table expression or within a trigger
+		if i+1 < len(f.tokens) && f.tokens[i+1].text == "(" {
+			return false
+		}
+		return i == 0 || f.tokens[i-1].text == ")" || f.tokens[i-1].text == ";" || f.word(i-1) == "BEGIN"
+	}
+	return true
+}
+
+// selectWidth returns the length the current line would have holding the select list starting at i
+func (f *formatter) selectWidth(i int) int {
+	width := len(f.buf) - (bytes.LastIndexByte(f.buf, '\n') + 1)
+	depth, items := 0, 1
+	for ; i < len(f.tokens); i++ {
+		tok := f.tokens[i]
+		switch tok.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				items++
+			}
+		case ";":
+			depth = -1
+		}
+		if depth < 0 {
+			break
+		}
+		if depth == 0 && tok.kind == tokenWord {
+			if n := f.match(i, clauses); n > 0 && f.words(i, n) != "SELECT" {
+				break
+			}
+		}
+		width += len(tok.text) + 1
+	}
+	if items == 1 {
+		return 0
+	}
+	return width
+}
+
+// match returns the number of words at i forming the longest of the keyword sequences, or 0
+func (f *formatter) match(i int, sequences [][]string) int {
+	best := 0
+	for _, seq := range sequences {
+		if len(seq) <= best {
+			continue
+		}
+		ok := true
+		for j, word := range seq {
+			if f.word(i+j) != word {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			best = len(seq)
+		}
+	}
+	return best
+}
+
+// word returns the upper-cased word at i, or an empty string when it is not a word
+func (f *formatter) word(i int) string {
+	if i >= len(f.tokens) || f.tokens[i].kind != tokenWord {
+		return ""
+	}
+	return strings.ToUpper(f.tokens[i].text)
+}
+
+// words returns the n upper-cased words starting at i joined with spaces
+func (f *formatter) words(i, n int) string {
+	words := make([]string, n)
+	for j := range words {
+		words[j] = f.word(i + j)
+	}
+	return strings.Join(words, " ")
+}
+
+// writeWords writes the n words starting at i, casing the keywords among them
+func (f *formatter) writeWords(i, n int, space bool) {
+	for j := i; j < i+n; j++ {
+		text := f.tokens[j].text
+		if keywords[strings.ToUpper(text)] && (j+1 >= len(f.tokens) || f.tokens[j+1].text != ".") &&
+			(j == 0 || f.tokens[j-1].text != ".") {
+			if f.opts.LowerKeywords {
+				text = strings.ToLower(text)
+			} else {
+				text = strings.ToUpper(text)
+			}
+		}
+		f.write(text, space || j > i)
+	}
+}
+
+// push opens a frame for the parenthesis just written
+func (f *formatter) push(kind frameKind) {
+	outer := f.lineIndent()
+	fr := &frame{kind: kind, outer: outer, indent: outer}
+	if kind != frameInline {
+		fr.indent = outer + 1
+		f.newline(fr.indent)
+	}
+	f.frames = append(f.frames, fr)
+}
+
+// lineIndent returns the indentation of the current line
+func (f *formatter) lineIndent() int {
+	line := f.buf[bytes.LastIndexByte(f.buf, '\n')+1:]
+	indent := 0
+	for bytes.HasPrefix(line, []byte(f.opts.Indent)) {
+		line = line[len(f.opts.Indent):]
+		indent++
+	}
+	return indent
+}
+
+func (f *formatter) newline(indent int) {
+	f.buf = bytes.TrimRight(f.buf, " ")
+	if len(f.buf) > 0 && f.buf[len(f.buf)-1] != '\n' {
+		f.buf = append(f.buf, '\n')
+	}
+	for i := 0; i < indent; i++ {
+		f.buf = append(f.buf, f.opts.Indent...)
+	}
+	f.lineStart = true
+}
+
+func (f *formatter) write(text string, space bool) {
+	if space && !f.lineStart && len(f.buf) > 0 {
+		f.buf = append(f.buf, ' ')
+	}
+	f.buf = append(f.buf, text...)
+	f.lineStart = false
+}
+
+// keywords are the SQLite keywords cased by Format, leaving out those commonly used as column names
+var keywords = func() map[string]bool {
+	keywords := map[string]bool{}
+	for _, keyword := range strings.Fields(`ABORT ADD AFTER ALL ALTER ALWAYS ANALYZE AND AS ASC ATTACH
+		AUTOINCREMENT BEFORE BEGIN BETWEEN BY CASCADE CASE CAST CHECK COLLATE COLUMN COMMIT CONFLICT CONSTRAINT
+		CREATE CROSS CURRENT_DATE CURRENT_TIME CURRENT_TIMESTAMP DEFAULT DEFERRABLE DEFERRED DELETE DESC DETACH
+		DISTINCT DO DROP EACH ELSE END ESCAPE EXCEPT EXCLUSIVE EXISTS EXPLAIN FAIL FILTER FOLLOWING FOR FOREIGN
+		FROM FULL GENERATED GLOB GROUP HAVING IF IGNORE IMMEDIATE IN INDEX INDEXED INITIALLY INNER INSERT
+		INSTEAD INTERSECT INTO IS ISNULL JOIN LEFT LIKE LIMIT MATCH MATERIALIZED NATURAL NOT NOTHING NOTNULL
+		NULL NULLS OF OFFSET ON OR ORDER OTHERS OUTER OVER PARTITION PRAGMA PRECEDING PRIMARY QUERY RAISE
+		RECURSIVE REFERENCES REGEXP REINDEX RELEASE RENAME REPLACE RESTRICT RETURNING RIGHT ROLLBACK SAVEPOINT
+		SELECT SET STORED STRICT TABLE TEMP TEMPORARY THEN TO TRANSACTION TRIGGER UNBOUNDED UNION UNIQUE UPDATE
+		USING VACUUM VALUES VIEW VIRTUAL WHEN WHERE WINDOW WITH WITHOUT`) {
+		keywords[keyword] = true
+	}
+	return keywords
+}()
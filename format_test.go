@@ -0,0 +1,120 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		query    string
+		opts     query.FormatOptions
+		expected string
+	}{
+		{
+			query.Select("users.id", "users.name", "users.email", "teams.name AS team", "count(posts.id) AS posts").
+				From("users").LeftJoin("teams", "teams.id = users.team_id AND teams.active = 1").
+				Where("users.active = ? AND users.created BETWEEN ? AND ? OR users.id IN (SELECT user_id FROM admins)").
+				GroupBy("users.id").OrderBy("users.name").Limit(10).String(),
+			query.FormatOptions{},
+			"SELECT\n" +
+				"  users.id,\n" +
+				"  users.name,\n" +
+				"  users.email,\n" +
+				"  teams.name AS team,\n" +
+				"  count(posts.id) AS posts\n" +
+				"FROM users\n" +
+				"LEFT JOIN teams ON teams.id = users.team_id\n" +
+				"  AND teams.active = 1\n" +
+				"WHERE users.active = ?\n" +
+				"  AND users.created BETWEEN ? AND ?\n" +
+				"  OR users.id IN (\n" +
+				"    SELECT user_id\n" +
+				"    FROM admins\n" +
+				"  )\n" +
+				"GROUP BY users.id\n" +
+				"ORDER BY users.name\n" +
+				"LIMIT ?",
+		},
+		{
+			"with recent as (select id, title from posts order by created desc) select * from recent where title = 'a; where b'",
+			query.FormatOptions{Indent: "\t"},
+			"WITH recent AS (\n" +
+				"\tSELECT id, title\n" +
+				"\tFROM posts\n" +
+				"\tORDER BY created DESC\n" +
+				")\n" +
+				"SELECT *\n" +
+				"FROM recent\n" +
+				"WHERE title = 'a; where b'",
+		},
+		{
+			"INSERT INTO users (name) VALUES (?) ON CONFLICT (name) DO NOTHING RETURNING id; DELETE FROM users WHERE \"order\" = 1",
+			query.FormatOptions{LowerKeywords: true},
+			"insert into users (name)\n" +
+				"values (?)\n" +
+				"on conflict (name) do nothing\n" +
+				"returning id;\n" +
+				"\n" +
+				"delete from users\n" +
+				"where \"order\" = 1",
+		},
+		{
+			"CREATE TABLE posts (id INTEGER PRIMARY KEY, author INTEGER REFERENCES users(id) ON DELETE SET NULL, title TEXT NOT NULL) STRICT;",
+			query.FormatOptions{},
+			"CREATE TABLE posts (\n" +
+				"  id INTEGER PRIMARY KEY,\n" +
+				"  author INTEGER REFERENCES users(id) ON DELETE SET NULL,\n" +
+				"  title TEXT NOT NULL\n" +
+				") STRICT;",
+		},
+		{
+			"CREATE TRIGGER audit AFTER UPDATE ON posts BEGIN INSERT INTO log (id) VALUES (new.id); UPDATE stats SET n = CASE WHEN n > 0 THEN n + 1 ELSE 1 END; END;",
+			query.FormatOptions{},
+			"CREATE TRIGGER audit AFTER UPDATE ON posts BEGIN\n" +
+				"  INSERT INTO log (id)\n" +
+				"  VALUES (new.id);\n" +
+				"  UPDATE stats\n" +
+				"  SET n = CASE WHEN n > 0 THEN n + 1 ELSE 1 END;\n" +
+				"END;",
+		},
+	}
+	for _, test := range tests {
+		q := query.Format(test.query, test.opts)
+		if q != test.expected {
+			t.Errorf("Expected query '%s', but got '%s'", test.expected, q)
+		}
+	}
+}
+
+func TestQueryFormat(t *testing.T) {
+	q := query.Select("id").From("users").Where("id = ?").Args(1)
+	expected := "SELECT id\nFROM users\nWHERE id = ?"
+	if formatted := q.Format(query.FormatOptions{}); formatted != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, formatted)
+	}
+
+	// Format does not reset the query
+	if s, args := q.Query(); s != "SELECT id FROM users WHERE id = ?" || len(args) != 1 {
+		t.Errorf("Expected the query to be kept, but got '%s' %v", s, args)
+	}
+}
+
+func TestScript(t *testing.T) {
+	script, err := query.Script(query.FormatOptions{},
+		query.CreateTable("foo", []query.Column{{Name: "id", Type: "INTEGER", PrimaryKey: true}}),
+		query.CreateIndex("foo_id", "foo", []string{"id"}, false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "CREATE TABLE foo (\n  id INTEGER PRIMARY KEY\n);\n\nCREATE INDEX foo_id ON foo (id);\n"
+	if script != expected {
+		t.Errorf("Expected script '%s', but got '%s'", expected, script)
+	}
+
+	if _, err := query.Script(query.FormatOptions{}, query.DeleteFrom("foo").Where("id = ?").Args(1)); err == nil {
+		t.Errorf("Expected an error for a query with arguments")
+	}
+}
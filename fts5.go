@@ -0,0 +1,205 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FTS5Table is a struct representing an FTS5 full-text search virtual table
+type FTS5Table struct {
+	Name    string
+	Columns []FTS5Column
+	// Tokenize is the tokenizer and its arguments, such as "porter unicode61 remove_diacritics 2"
+	Tokenize string
+	// Prefix holds the lengths of the prefixes indexed for prefix queries
+	Prefix []int
+	// Content is the table holding the indexed rows of an external content table, and ContentRowID its key
+	Content      string
+	ContentRowID string
+	// Contentless declares a contentless table, which stores no copy of the indexed rows
+	Contentless bool
+	// Detail is the detail option: full, column or none
+	Detail string
+}
+
+// FTS5Column is a struct representing a column of an FTS5 table, which is stored but not indexed when Unindexed
+type FTS5Column struct {
+	Name      string
+	Unindexed bool
+}
+
+// CreateFTS5 is a function that returns a CREATE VIRTUAL TABLE query for the specified FTS5 table
+func CreateFTS5(table FTS5Table) *Query {
+	return getQuery().CreateFTS5(table)
+}
+
+// CreateFTS5 is a function that returns a CREATE VIRTUAL TABLE query for the specified FTS5 table
+func (q *Query) CreateFTS5(table FTS5Table) *Query {
+	q.query = append(q.query, "CREATE VIRTUAL TABLE "...)
+	q.query = append(q.query, table.Name...)
+	q.query = append(q.query, " USING fts5("...)
+	for i, column := range table.Columns {
+		if i > 0 {
+			q.query = append(q.query, ", "...)
+		}
+		q.query = append(q.query, column.Name...)
+		if column.Unindexed {
+			q.query = append(q.query, " UNINDEXED"...)
+		}
+	}
+	option := func(name, value string) {
+		q.query = append(q.query, ", "...)
+		q.query = append(q.query, name...)
+		q.query = append(q.query, " = "...)
+		q.query = append(q.query, quoteLiteral(value)...)
+	}
+	if table.Tokenize != "" {
+		option("tokenize", table.Tokenize)
+	}
+	if len(table.Prefix) > 0 {
+		prefixes := make([]string, len(table.Prefix))
+		for i, prefix := range table.Prefix {
+			prefixes[i] = strconv.Itoa(prefix)
+		}
+		option("prefix", strings.Join(prefixes, " "))
+	}
+	switch {
+	case table.Contentless:
+		option("content", "")
+	case table.Content != "":
+		option("content", table.Content)
+		if table.ContentRowID != "" {
+			option("content_rowid", table.ContentRowID)
+		}
+	}
+	if table.Detail != "" {
+		option("detail", table.Detail)
+	}
+	q.query = append(q.query, ");"...)
+	return q
+}
+
+// CreateFTS5Triggers is a function that returns the CREATE TRIGGER queries keeping an external content FTS5
+// table in sync with its content table on insert, update and delete. The triggers are named after the FTS5
+// table suffixed with _ai, _ad and _au.
+func CreateFTS5Triggers(table FTS5Table) *Query {
+	return getQuery().CreateFTS5Triggers(table)
+}
+
+// CreateFTS5Triggers is a function that returns the CREATE TRIGGER queries keeping an external content FTS5
+// table in sync with its content table on insert, update and delete
+func (q *Query) CreateFTS5Triggers(table FTS5Table) *Query {
+	if table.Content == "" || table.Contentless {
+		q.setErr(fmt.Errorf("query: FTS5 table %s has no external content table to sync", table.Name))
+		return q
+	}
+	rowid := table.ContentRowID
+	if rowid == "" {
+		rowid = "rowid"
+	}
+	columns := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		columns[i] = column.Name
+	}
+	values := func(row string) string {
+		values := make([]string, 0, len(columns)+1)
+		values = append(values, row+"."+rowid)
+		for _, column := range columns {
+			values = append(values, row+"."+column)
+		}
+		return strings.Join(values, ", ")
+	}
+	// statements of triggers cannot qualify the tables they write
+	name, list := unqualified(table.Name), strings.Join(columns, ", ")
+	insert := "INSERT INTO " + name + " (rowid, " + list + ") VALUES (" + values("new") + ");"
+	remove := "INSERT INTO " + name + " (" + name + ", rowid, " + list + ") VALUES ('delete', " + values("old") + ");"
+
+	q.CreateTrigger(table.Name+"_ai", table.Content, "AFTER", "INSERT", "BEGIN "+insert+" END")
+	q.query = append(q.query, ' ')
+	q.CreateTrigger(table.Name+"_ad", table.Content, "AFTER", "DELETE", "BEGIN "+remove+" END")
+	q.query = append(q.query, ' ')
+	q.CreateTrigger(table.Name+"_au", table.Content, "AFTER", "UPDATE", "BEGIN "+remove+" "+insert+" END")
+	return q
+}
+
+// FTS5Rebuild is a function that returns the FTS5 command rebuilding the index of a table from its content
+func FTS5Rebuild(tableName string) *Query {
+	return getQuery().FTS5Rebuild(tableName)
+}
+
+// FTS5Rebuild is a function that returns the FTS5 command rebuilding the index of a table from its content
+func (q *Query) FTS5Rebuild(tableName string) *Query {
+	return q.fts5Command(tableName, "rebuild")
+}
+
+// FTS5Optimize is a function that returns the FTS5 command merging the index of a table into a single b-tree
+func FTS5Optimize(tableName string) *Query {
+	return getQuery().FTS5Optimize(tableName)
+}
+
+// FTS5Optimize is a function that returns the FTS5 command merging the index of a table into a single b-tree
+func (q *Query) FTS5Optimize(tableName string) *Query {
+	return q.fts5Command(tableName, "optimize")
+}
+
+// FTS5IntegrityCheck is a function that returns the FTS5 command checking the index of a table against its content
+func FTS5IntegrityCheck(tableName string) *Query {
+	return getQuery().FTS5IntegrityCheck(tableName)
+}
+
+// FTS5IntegrityCheck is a function that returns the FTS5 command checking the index of a table against its content
+func (q *Query) FTS5IntegrityCheck(tableName string) *Query {
+	return q.fts5Command(tableName, "integrity-check")
+}
+
+// FTS5DeleteAll is a function that returns the FTS5 command deleting the whole index of a contentless or
+// external content table
+func FTS5DeleteAll(tableName string) *Query {
+	return getQuery().FTS5DeleteAll(tableName)
+}
+
+// FTS5DeleteAll is a function that returns the FTS5 command deleting the whole index of a contentless or
+// external content table
+func (q *Query) FTS5DeleteAll(tableName string) *Query {
+	return q.fts5Command(tableName, "delete-all")
+}
+
+// FTS5Merge is a function that returns the FTS5 command merging index segments of a table, writing about
+// the specified number of pages
+func FTS5Merge(tableName string, pages int) *Query {
+	return getQuery().FTS5Merge(tableName, pages)
+}
+
+// FTS5Merge is a function that returns the FTS5 command merging index segments of a table, writing about
+// the specified number of pages
+func (q *Query) FTS5Merge(tableName string, pages int) *Query {
+	q.fts5Insert(tableName, ", rank")
+	q.query = append(q.query, "'merge', ?);"...)
+	q.args = append(q.args, pages)
+	return q
+}
+
+// fts5Command appends an FTS5 command, which inserts the command name into the column named after the table
+func (q *Query) fts5Command(tableName, command string) *Query {
+	q.fts5Insert(tableName, "")
+	q.query = append(q.query, '\'')
+	q.query = append(q.query, command...)
+	q.query = append(q.query, "');"...)
+	return q
+}
+
+// fts5Insert appends the start of an INSERT into the column named after an FTS5 table and the extra columns
+func (q *Query) fts5Insert(tableName, columns string) {
+	q.query = append(q.query, "INSERT INTO "...)
+	q.query = append(q.query, tableName...)
+	q.query = append(q.query, " ("...)
+	q.query = append(q.query, unqualified(tableName)...)
+	q.query = append(q.query, columns...)
+	q.query = append(q.query, ") VALUES ("...)
+}
+
+// unqualified returns a table name without its schema
+func unqualified(tableName string) string {
+	return tableName[strings.LastIndexByte(tableName, '.')+1:]
+}
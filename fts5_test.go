@@ -0,0 +1,76 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+var postsFTS = query.FTS5Table{
+	Name:         "posts_fts",
+	Columns:      []query.FTS5Column{{Name: "title"}, {Name: "body"}, {Name: "lang", Unindexed: true}},
+	Tokenize:     "unicode61 tokenchars '-_'",
+	Prefix:       []int{2, 3},
+	Content:      "posts",
+	ContentRowID: "id",
+}
+
+func TestCreateFTS5(t *testing.T) {
+	q := query.CreateFTS5(postsFTS).String()
+	expected := "CREATE VIRTUAL TABLE posts_fts USING fts5(title, body, lang UNINDEXED, tokenize = 'unicode61 tokenchars ''-_''', " +
+		"prefix = '2 3', content = 'posts', content_rowid = 'id');"
+	if q != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, q)
+	}
+
+	q = query.CreateFTS5(query.FTS5Table{Name: "logs", Columns: []query.FTS5Column{{Name: "line"}}, Contentless: true, Detail: "none"}).String()
+	expected = "CREATE VIRTUAL TABLE logs USING fts5(line, content = '', detail = 'none');"
+	if q != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, q)
+	}
+
+	if statement := query.Classify(expected); statement.Kind != query.KindDDL {
+		t.Errorf("Expected the query to be classified as DDL, but got %s", statement.Kind)
+	}
+}
+
+func TestCreateFTS5Triggers(t *testing.T) {
+	q := query.CreateFTS5Triggers(postsFTS).String()
+	expected := "CREATE TRIGGER posts_fts_ai AFTER INSERT ON posts BEGIN " +
+		"INSERT INTO posts_fts (rowid, title, body, lang) VALUES (new.id, new.title, new.body, new.lang); END; " +
+		"CREATE TRIGGER posts_fts_ad AFTER DELETE ON posts BEGIN " +
+		"INSERT INTO posts_fts (posts_fts, rowid, title, body, lang) VALUES ('delete', old.id, old.title, old.body, old.lang); END; " +
+		"CREATE TRIGGER posts_fts_au AFTER UPDATE ON posts BEGIN " +
+		"INSERT INTO posts_fts (posts_fts, rowid, title, body, lang) VALUES ('delete', old.id, old.title, old.body, old.lang); " +
+		"INSERT INTO posts_fts (rowid, title, body, lang) VALUES (new.id, new.title, new.body, new.lang); END;"
+	if q != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, q)
+	}
+
+	if _, _, err := query.CreateFTS5Triggers(query.FTS5Table{Name: "notes_fts"}).Build(); err == nil {
+		t.Error("Expected an error for a table without external content")
+	}
+}
+
+func TestFTS5Commands(t *testing.T) {
+	tests := []struct {
+		q        *query.Query
+		expected string
+	}{
+		{query.FTS5Rebuild("posts_fts"), "INSERT INTO posts_fts (posts_fts) VALUES ('rebuild');"},
+		{query.FTS5Optimize("main.posts_fts"), "INSERT INTO main.posts_fts (posts_fts) VALUES ('optimize');"},
+		{query.FTS5IntegrityCheck("posts_fts"), "INSERT INTO posts_fts (posts_fts) VALUES ('integrity-check');"},
+		{query.FTS5DeleteAll("posts_fts"), "INSERT INTO posts_fts (posts_fts) VALUES ('delete-all');"},
+		{query.FTS5Merge("posts_fts", 500), "INSERT INTO posts_fts (posts_fts, rank) VALUES ('merge', ?);"},
+	}
+
+	for _, test := range tests {
+		q, _, err := test.q.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q != test.expected {
+			t.Errorf("Expected query '%s', but got '%s'", test.expected, q)
+		}
+	}
+}
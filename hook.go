@@ -0,0 +1,229 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Hook is an interface implemented by observers of the queries run on an executor returned by WithHooks
+type Hook interface {
+	// BeforeQuery is called before the query runs and returns the context it runs with
+	BeforeQuery(ctx context.Context, query string, args []any) context.Context
+	// AfterQuery is called once the query has run, with the time it took and the error it returned
+	AfterQuery(ctx context.Context, query string, args []any, duration time.Duration, err error)
+}
+
+// HookExecutor is a struct implementing Executor that calls hooks around every query run on an executor
+type HookExecutor struct {
+	db    Executor
+	hooks []Hook
+}
+
+// WithHooks is a function that wraps an executor so that the hooks observe every query run on it. BeforeQuery
+// is called in the order of the hooks and AfterQuery in the reverse order.
+func WithHooks(db Executor, hooks ...Hook) *HookExecutor {
+	if h, ok := db.(*HookExecutor); ok {
+		return &HookExecutor{db: h.db, hooks: append(append([]Hook(nil), h.hooks...), hooks...)}
+	}
+	return &HookExecutor{db: db, hooks: hooks}
+}
+
+// ExecContext is a function that executes a query string on the executor between the hooks
+func (h *HookExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx = h.before(ctx, query, args)
+	start := time.Now()
+	res, err := h.db.ExecContext(ctx, query, args...)
+	h.after(ctx, query, args, time.Since(start), err)
+	return res, err
+}
+
+// QueryContext is a function that runs a query string on the executor between the hooks. The duration
+// passed to AfterQuery ends when the rows are returned, before they are read.
+func (h *HookExecutor) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx = h.before(ctx, query, args)
+	start := time.Now()
+	rows, err := h.db.QueryContext(ctx, query, args...)
+	h.after(ctx, query, args, time.Since(start), err)
+	return rows, err
+}
+
+func (h *HookExecutor) before(ctx context.Context, query string, args []any) context.Context {
+	for _, hook := range h.hooks {
+		ctx = hook.BeforeQuery(ctx, query, args)
+	}
+	return ctx
+}
+
+func (h *HookExecutor) after(ctx context.Context, query string, args []any, duration time.Duration, err error) {
+	for i := len(h.hooks) - 1; i >= 0; i-- {
+		h.hooks[i].AfterQuery(ctx, query, args, duration, err)
+	}
+}
+
+// LogHook is a struct implementing Hook that logs every query with log/slog
+type LogHook struct {
+	// Logger is the logger written to, slog.Default() when nil
+	Logger *slog.Logger
+	// Level is the level of successful queries, failed ones are logged at slog.LevelError
+	Level slog.Level
+	// Args inlines the arguments in the logged query with Interpolate, redacting sensitive ones
+	Args bool
+}
+
+// BeforeQuery is a function that implements Hook and returns ctx unchanged
+func (h LogHook) BeforeQuery(ctx context.Context, query string, args []any) context.Context {
+	return ctx
+}
+
+// AfterQuery is a function that implements Hook and logs the query, its duration and error
+func (h LogHook) AfterQuery(ctx context.Context, query string, args []any, duration time.Duration, err error) {
+	level := h.Level
+	attrs := []slog.Attr{slog.Duration("duration", duration)}
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logQuery(ctx, h.Logger, level, "query", query, args, h.Args, attrs)
+}
+
+// SlowQueryHook is a struct implementing Hook that logs the queries taking longer than a threshold with log/slog
+type SlowQueryHook struct {
+	// Threshold is the duration above which a query is logged
+	Threshold time.Duration
+	// Logger is the logger written to at slog.LevelWarn, slog.Default() when nil
+	Logger *slog.Logger
+	// Args inlines the arguments in the logged query with Interpolate, redacting sensitive ones
+	Args bool
+}
+
+// BeforeQuery is a function that implements Hook and returns ctx unchanged
+func (h SlowQueryHook) BeforeQuery(ctx context.Context, query string, args []any) context.Context {
+	return ctx
+}
+
+// AfterQuery is a function that implements Hook and logs the query when it exceeded the threshold
+func (h SlowQueryHook) AfterQuery(ctx context.Context, query string, args []any, duration time.Duration, err error) {
+	if duration <= h.Threshold {
+		return
+	}
+	attrs := []slog.Attr{slog.Duration("duration", duration), slog.Duration("threshold", h.Threshold)}
+	logQuery(ctx, h.Logger, slog.LevelWarn, "slow query", query, args, h.Args, attrs)
+}
+
+func logQuery(ctx context.Context, logger *slog.Logger, level slog.Level, msg, query string, args []any, inline bool, attrs []slog.Attr) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	if inline {
+		query = Interpolate(query, args...)
+	}
+	logger.LogAttrs(ctx, level, msg, append([]slog.Attr{slog.String("sql", query)}, attrs...)...)
+}
+
+// DefaultLatencyBuckets are the upper bounds of the buckets of a LatencyHistogram created without any
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second,
+}
+
+// LatencyHistogram is a struct implementing Hook that records the latencies of the queries in memory, grouped
+// by query fingerprint. The zero value is ready to use with DefaultLatencyBuckets.
+type LatencyHistogram struct {
+	bounds []time.Duration
+	mu     sync.Mutex
+	stats  map[string]*LatencyStats
+}
+
+// LatencyStats is a struct holding the latencies recorded for the queries of a fingerprint
+type LatencyStats struct {
+	// Query is the normalized text of the fingerprint
+	Query  string
+	Count  int64
+	Errors int64
+	Total  time.Duration
+	Max    time.Duration
+	// Bounds are the upper bounds of the buckets
+	Bounds []time.Duration
+	// Buckets are the number of queries per bucket, the last one counting those above every bound
+	Buckets []int64
+}
+
+// Mean is a function that returns the mean latency of the query
+func (s LatencyStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// NewLatencyHistogram is a function that returns a histogram with the specified bucket bounds,
+// DefaultLatencyBuckets when none
+func NewLatencyHistogram(bounds ...time.Duration) *LatencyHistogram {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBuckets
+	}
+	bounds = append([]time.Duration(nil), bounds...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+	return &LatencyHistogram{bounds: bounds, stats: map[string]*LatencyStats{}}
+}
+
+// BeforeQuery is a function that implements Hook and returns ctx unchanged
+func (h *LatencyHistogram) BeforeQuery(ctx context.Context, query string, args []any) context.Context {
+	return ctx
+}
+
+// AfterQuery is a function that implements Hook and records the duration of the query
+func (h *LatencyHistogram) AfterQuery(ctx context.Context, query string, args []any, duration time.Duration, err error) {
+	key := Fingerprint(query).Text
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stats == nil {
+		h.stats = map[string]*LatencyStats{}
+	}
+	if h.bounds == nil {
+		h.bounds = DefaultLatencyBuckets
+	}
+	s, ok := h.stats[key]
+	if !ok {
+		s = &LatencyStats{Query: key, Bounds: h.bounds, Buckets: make([]int64, len(h.bounds)+1)}
+		h.stats[key] = s
+	}
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.Total += duration
+	if duration > s.Max {
+		s.Max = duration
+	}
+	s.Buckets[sort.Search(len(h.bounds), func(i int) bool { return duration <= h.bounds[i] })]++
+}
+
+// Snapshot is a function that returns a copy of the recorded latencies, sorted by normalized query
+func (h *LatencyHistogram) Snapshot() []LatencyStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	snapshot := make([]LatencyStats, 0, len(h.stats))
+	for _, s := range h.stats {
+		c := *s
+		c.Buckets = append([]int64(nil), s.Buckets...)
+		snapshot = append(snapshot, c)
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].Query < snapshot[j].Query })
+	return snapshot
+}
+
+// Reset is a function that discards the recorded latencies
+func (h *LatencyHistogram) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats = map[string]*LatencyStats{}
+}
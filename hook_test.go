@@ -0,0 +1,116 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+type keyHook struct {
+	name  string
+	calls *[]string
+}
+
+type hookKey struct{}
+
+func (h keyHook) BeforeQuery(ctx context.Context, q string, args []any) context.Context {
+	*h.calls = append(*h.calls, "before "+h.name)
+	return context.WithValue(ctx, hookKey{}, h.name)
+}
+
+func (h keyHook) AfterQuery(ctx context.Context, q string, args []any, duration time.Duration, err error) {
+	*h.calls = append(*h.calls, "after "+h.name+" "+ctx.Value(hookKey{}).(string))
+}
+
+func TestWithHooks(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("DELETE FROM foo WHERE id = ?").WithArgs(1).WillReturnResult(0, 1)
+
+	var calls []string
+	hooked := query.WithHooks(query.WithHooks(db, keyHook{"a", &calls}), keyHook{"b", &calls})
+	if _, err := query.Exec(context.Background(), hooked, query.DeleteFrom("foo").Where("id = ?").Args(1)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"before a", "before b", "after b b", "after a b"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected calls %q, but got %q", expected, calls)
+	}
+}
+
+func TestLogHooks(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("SELECT * FROM users WHERE password = ?").WillReturnError(errors.New("no such table: users"))
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	hooked := query.WithHooks(db,
+		query.LogHook{Logger: logger, Level: slog.LevelDebug, Args: true},
+		query.SlowQueryHook{Threshold: -1, Logger: logger},
+	)
+
+	rows, err := query.QueryRows(context.Background(), hooked, query.Select("*").From("users").Where("password = ?").Args(query.Sensitive("hunter2")))
+	if err == nil {
+		rows.Close()
+		t.Fatal("Expected an error")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, but got %q", lines)
+	}
+	if !strings.Contains(lines[0], `level=WARN msg="slow query" sql="SELECT * FROM users WHERE password = ?"`) {
+		t.Errorf("Expected the slow query, but got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `level=ERROR msg=query sql="SELECT * FROM users WHERE password = '[REDACTED]'"`) ||
+		!strings.Contains(lines[1], `error="no such table: users"`) {
+		t.Errorf("Expected the failed query, but got %q", lines[1])
+	}
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("Expected the sensitive argument to be redacted, but got %q", buf.String())
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	h := query.NewLatencyHistogram(10*time.Millisecond, time.Millisecond)
+	ctx := context.Background()
+	h.AfterQuery(ctx, "SELECT 1", nil, 500*time.Microsecond, nil)
+	h.AfterQuery(ctx, "SELECT  1\n", nil, 5*time.Millisecond, errors.New("boom"))
+	h.AfterQuery(ctx, "SELECT 1", nil, time.Second, nil)
+	h.AfterQuery(ctx, "DELETE FROM foo", nil, time.Millisecond, nil)
+
+	snapshot := h.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("Expected 2 queries, but got %d", len(snapshot))
+	}
+	s := snapshot[1]
+	if s.Query != "SELECT ?" || s.Count != 3 || s.Errors != 1 || s.Max != time.Second ||
+		!reflect.DeepEqual(s.Buckets, []int64{1, 1, 1}) || s.Mean() != (1005500*time.Microsecond)/3 {
+		t.Errorf("Expected the SELECT 1 latencies, but got %+v", s)
+	}
+	if s := snapshot[0]; s.Query != "DELETE FROM foo" || !reflect.DeepEqual(s.Buckets, []int64{1, 0, 0}) {
+		t.Errorf("Expected the DELETE latencies, but got %+v", s)
+	}
+
+	h.Reset()
+	if snapshot := h.Snapshot(); len(snapshot) != 0 {
+		t.Errorf("Expected no queries after Reset, but got %d", len(snapshot))
+	}
+}
+
+func TestLatencyHistogramZeroValue(t *testing.T) {
+	var h query.LatencyHistogram
+	h.AfterQuery(context.Background(), "SELECT 1", nil, 2*time.Millisecond, nil)
+
+	snapshot := h.Snapshot()
+	if len(snapshot) != 1 || snapshot[0].Count != 1 || len(snapshot[0].Buckets) != len(query.DefaultLatencyBuckets)+1 {
+		t.Errorf("Expected the latency in the default buckets, but got %+v", snapshot)
+	}
+}
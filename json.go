@@ -0,0 +1,214 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a function that returns the JSON path selecting the specified object keys and array indexes,
+// quoting the keys that are not plain identifiers. A negative index counts from the end of the array.
+// SQLite paths cannot escape a double quote, so a key containing one yields the empty path, which the JSON
+// expressions of this package reject with an error rather than selecting another key.
+func JSONPath(elements ...any) string {
+	path := []byte{'$'}
+	for _, element := range elements {
+		switch e := element.(type) {
+		case int:
+			path = append(path, '[')
+			if e < 0 {
+				path = append(path, "#-"...)
+				e = -e
+			}
+			path = strconv.AppendInt(path, int64(e), 10)
+			path = append(path, ']')
+		case string:
+			path = append(path, '.')
+			switch {
+			case strings.Contains(e, `"`):
+				return ""
+			case isPlainKey(e):
+				path = append(path, e...)
+			default:
+				path = append(path, '"')
+				path = append(path, e...)
+				path = append(path, '"')
+			}
+		}
+	}
+	return string(path)
+}
+
+// isPlainKey reports whether an object key can be written unquoted in a JSON path
+func isPlainKey(key string) bool {
+	if key == "" || isDigit(key[0]) {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if !isWordByte(key[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// JSONPathValue is a struct holding a JSON path and the value json_set, json_insert or json_replace writes there
+type JSONPathValue struct {
+	Path  string
+	Value any
+}
+
+// JSON is a function that returns a json() expression of a value bound as JSON text, so that it is stored as
+// a JSON object or array rather than a string
+func JSON(value any) Expr {
+	return Expr{SQL: "json(?)", Args: []any{AsJSON(value)}}
+}
+
+// JSONExtract is a function that returns a json_extract expression of the values at the paths of a column
+func JSONExtract(column string, paths ...string) Expr {
+	return jsonFunc("json_extract", column, paths)
+}
+
+// JSONGet is a function that returns a -> expression, which extracts the JSON text at the path of a column
+func JSONGet(column, path string) Expr {
+	return Expr{SQL: column + " -> ?", Args: []any{path}, err: checkJSONPath(path)}
+}
+
+// JSONGetText is a function that returns a ->> expression, which extracts the SQL value at the path of a column
+func JSONGetText(column, path string) Expr {
+	return Expr{SQL: column + " ->> ?", Args: []any{path}, err: checkJSONPath(path)}
+}
+
+// JSONType is a function that returns a json_type expression of the value at the path of a column
+func JSONType(column string, paths ...string) Expr {
+	return jsonFunc("json_type", column, paths)
+}
+
+// JSONArrayLength is a function that returns a json_array_length expression of the array at the path of a column
+func JSONArrayLength(column string, paths ...string) Expr {
+	return jsonFunc("json_array_length", column, paths)
+}
+
+// JSONSet is a function that returns a json_set expression writing the values at their paths of a column.
+// Maps, slices, structs with json tags and AsJSON values are written as JSON rather than strings.
+func JSONSet(column string, values ...JSONPathValue) Expr {
+	return jsonEdit("json_set", column, values)
+}
+
+// JSONInsert is a function that returns a json_insert expression writing the values at the paths of a
+// column that do not exist yet
+func JSONInsert(column string, values ...JSONPathValue) Expr {
+	return jsonEdit("json_insert", column, values)
+}
+
+// JSONReplace is a function that returns a json_replace expression writing the values at the paths of a
+// column that already exist
+func JSONReplace(column string, values ...JSONPathValue) Expr {
+	return jsonEdit("json_replace", column, values)
+}
+
+// JSONRemove is a function that returns a json_remove expression removing the paths of a column
+func JSONRemove(column string, paths ...string) Expr {
+	return jsonFunc("json_remove", column, paths)
+}
+
+// JSONGroupArray is a function that returns a json_group_array aggregate of an expression
+func JSONGroupArray(expr string) Expr {
+	return Expr{SQL: "json_group_array(" + expr + ")"}
+}
+
+// JSONGroupObject is a function that returns a json_group_object aggregate of key and value expressions
+func JSONGroupObject(key, value string) Expr {
+	return Expr{SQL: "json_group_object(" + key + ", " + value + ")"}
+}
+
+// JSONEach is a function that returns a json_each table-valued function over the elements of a column, or of
+// the value at a path of it, for FromExpr and JoinExpr
+func JSONEach(column string, path ...string) Expr {
+	return jsonFunc("json_each", column, path)
+}
+
+// JSONTree is a function that returns a json_tree table-valued function walking a column recursively, or the
+// value at a path of it, for FromExpr and JoinExpr
+func JSONTree(column string, path ...string) Expr {
+	return jsonFunc("json_tree", column, path)
+}
+
+// jsonFunc returns a call of a JSON function on a column with the paths bound as arguments
+func jsonFunc(name, column string, paths []string) Expr {
+	var sb strings.Builder
+	sb.WriteString(name + "(" + column)
+	args := make([]any, len(paths))
+	var err error
+	for i, path := range paths {
+		sb.WriteString(", ?")
+		args[i] = path
+		if err == nil {
+			err = checkJSONPath(path)
+		}
+	}
+	sb.WriteString(")")
+	return Expr{SQL: sb.String(), Args: args, err: err}
+}
+
+// jsonEdit returns a call of a JSON function writing values at paths of a column
+func jsonEdit(name, column string, values []JSONPathValue) Expr {
+	var sb strings.Builder
+	sb.WriteString(name + "(" + column)
+	args := make([]any, 0, len(values)*2)
+	var err error
+	for _, value := range values {
+		sb.WriteString(", ?, ")
+		args = append(args, value.Path)
+		if err == nil {
+			err = checkJSONPath(value.Path)
+		}
+		if expr, ok := value.Value.(Expr); ok {
+			sb.WriteString(expr.SQL)
+			args = append(args, expr.Args...)
+			if err == nil {
+				err = expr.err
+			}
+		} else if isJSON(value.Value) {
+			sb.WriteString("json(?)")
+			args = append(args, value.Value)
+		} else {
+			sb.WriteString("?")
+			args = append(args, value.Value)
+		}
+	}
+	sb.WriteString(")")
+	return Expr{SQL: sb.String(), Args: args, err: err}
+}
+
+// checkJSONPath rejects the empty path returned by JSONPath for a key it cannot quote
+func checkJSONPath(path string) error {
+	if path == "" {
+		return errors.New("query: empty JSON path, JSONPath cannot quote a key containing a double quote")
+	}
+	return nil
+}
+
+// isJSON reports whether a value is converted to JSON text by Converters
+func isJSON(value any) bool {
+	if _, ok := value.(JSONValue); ok {
+		return true
+	}
+	typ := reflect.TypeOf(value)
+	if typ == nil || typ.Implements(valuerType) {
+		return false
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice, reflect.Array:
+		return typ.Elem().Kind() != reflect.Uint8
+	case reflect.Struct:
+		return typ != timeType && hasJSONTags(typ)
+	}
+	return false
+}
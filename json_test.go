@@ -0,0 +1,120 @@
+package query_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		elements []any
+		expected string
+	}{
+		{nil, "$"},
+		{[]any{"address", "city"}, "$.address.city"},
+		{[]any{"tags", 0}, "$.tags[0]"},
+		{[]any{"tags", -1}, "$.tags[#-1]"},
+		{[]any{"first name", "a.b", "2fa"}, `$."first name"."a.b"."2fa"`},
+		{[]any{"profile", `say "hi"`}, ""},
+	}
+
+	for _, test := range tests {
+		if path := query.JSONPath(test.elements...); path != test.expected {
+			t.Errorf("Expected path '%s', but got '%s'", test.expected, path)
+		}
+	}
+}
+
+func TestJSONSelect(t *testing.T) {
+	query, args, err := query.SelectExpr(
+		query.Expr{SQL: "users.id"},
+		query.JSONExtract("users.data", query.JSONPath("name")).As("name"),
+		query.JSONGetText("users.data", "$.email"),
+		query.JSONGroupArray("tag.value").As("tags"),
+	).
+		From("users").
+		JoinExpr(query.JSONEach("users.data", "$.tags").As("tag"), "").
+		WhereExpr(query.JSONGet("users.data", "$.address").Compare("IS NOT", nil)).
+		GroupBy("users.id").
+		OrderByExpr(query.JSONExtract("users.data", "$.age").Desc()).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SELECT users.id, json_extract(users.data, ?) AS name, users.data ->> ?, json_group_array(tag.value) AS tags " +
+		"FROM users JOIN json_each(users.data, ?) AS tag WHERE users.data -> ? IS NOT ? GROUP BY users.id " +
+		"ORDER BY json_extract(users.data, ?) DESC"
+	if query != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, query)
+	}
+	expectedArgs := []any{"$.name", "$.email", "$.tags", "$.address", nil, "$.age"}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected args %v, but got %v", expectedArgs, args)
+	}
+}
+
+func TestJSONTreeSource(t *testing.T) {
+	q := query.Select("tree.fullkey", "tree.atom").FromExpr(query.Expr{SQL: "docs"}, query.JSONTree("docs.body").As("tree")).
+		WhereExpr(query.JSONType("docs.body", "$.items").Eq("array"))
+	expected := "SELECT tree.fullkey, tree.atom FROM docs, json_tree(docs.body) AS tree WHERE json_type(docs.body, ?) = ?"
+	if query, args := q.Query(); query != expected || len(args) != 2 {
+		t.Errorf("Expected query '%s', but got '%s' %v", expected, query, args)
+	}
+}
+
+func TestJSONSet(t *testing.T) {
+	query, args, err := query.Update("docs", "").Set([]*query.Field{
+		{Name: "body", Value: query.JSONSet("body",
+			query.JSONPathValue{Path: "$.title", Value: "It's"},
+			query.JSONPathValue{Path: "$.tags", Value: []string{"a", "b"}},
+			query.JSONPathValue{Path: "$.meta", Value: query.JSON(map[string]int{"v": 1})},
+		)},
+		{Name: "extra", Value: query.JSONRemove("extra", "$.old", "$.older")},
+		{Name: "version", Value: 2},
+	}).Where("id = ?").Args(7).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "UPDATE docs SET body = json_set(body, ?, ?, ?, json(?), ?, json(?)), extra = json_remove(extra, ?, ?), version = ? WHERE id = ?"
+	if query != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, query)
+	}
+	expectedArgs := []any{"$.title", "It's", "$.tags", `["a","b"]`, "$.meta", `{"v":1}`, "$.old", "$.older", 2, 7}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected args %v, but got %v", expectedArgs, args)
+	}
+}
+
+func TestJSONValues(t *testing.T) {
+	q := query.InsertInto("docs").Columns("id", "body").Values(1, query.JSON(map[string]any{"a": true}))
+	expected := `INSERT INTO docs (id, body) VALUES (1, json('{"a":true}'))`
+	if debug := q.Debug(); debug != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, debug)
+	}
+	q.Reset()
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	path := query.JSONPath("profile", `say "hi"`)
+	exprs := []query.Expr{
+		query.JSONExtract("body", "$.name", path),
+		query.JSONGet("body", path),
+		query.JSONGetText("body", path),
+		query.JSONSet("body", query.JSONPathValue{Path: path, Value: 1}),
+		query.JSONSet("body", query.JSONPathValue{Path: "$.name", Value: query.JSONExtract("other", path)}),
+	}
+	for _, expr := range exprs {
+		if expr.Err() == nil {
+			t.Errorf("Expected an error for the empty path in '%s'", expr.SQL)
+		}
+	}
+
+	_, _, err := query.Update("docs", "").Set([]*query.Field{{Name: "body", Value: exprs[3]}}).Build()
+	if err == nil {
+		t.Errorf("Expected Build to report the empty path")
+	}
+}
@@ -0,0 +1,282 @@
+package query
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidCursor is the error returned by DecodeCursor for a cursor it did not encode
+var ErrInvalidCursor = errors.New("query: invalid cursor")
+
+// SortKey is a struct holding a column a keyset is sorted by and its direction
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// Keyset is a struct describing keyset pagination, which pages through rows by comparing their sort keys with
+// those of the last row of the previous page rather than skipping rows with OFFSET. The sort keys must not be
+// NULL and the last one must be unique, such as the primary key, for the order to be total.
+type Keyset struct {
+	Keys  []SortKey
+	Limit int
+}
+
+// Cursor is a struct holding the sort key values of the row a page starts after, or ends before when paging
+// backward. The zero cursor selects the first page.
+type Cursor struct {
+	Values   []any
+	Backward bool
+}
+
+// After is a function that returns the cursor of the page following the row with the specified sort key values
+func (k Keyset) After(values ...any) Cursor {
+	return Cursor{Values: values}
+}
+
+// Before is a function that returns the cursor of the page preceding the row with the specified sort key values
+func (k Keyset) Before(values ...any) Cursor {
+	return Cursor{Values: values, Backward: true}
+}
+
+// cursorJSON is the encoded form of a cursor, where blobs are held as {"x": hex}
+type cursorJSON struct {
+	Values   []any `json:"v"`
+	Backward bool  `json:"b,omitempty"`
+}
+
+// Encode is a function that returns the cursor encoded as an opaque URL-safe string, converting its values
+// with DefaultConverters. It returns an error for a value that cannot be converted or encoded, such as NaN.
+func (c Cursor) Encode() (string, error) {
+	if len(c.Values) == 0 {
+		return "", nil
+	}
+	encoded := cursorJSON{Values: make([]any, len(c.Values)), Backward: c.Backward}
+	for i, value := range c.Values {
+		converted, err := DefaultConverters.Convert(value)
+		if err != nil {
+			return "", fmt.Errorf("query: encoding cursor value %d: %w", i+1, err)
+		}
+		if b, ok := converted.([]byte); ok {
+			converted = map[string]string{"x": hex.EncodeToString(b)}
+		}
+		encoded.Values[i] = converted
+	}
+	b, err := json.Marshal(encoded)
+	if err != nil {
+		return "", fmt.Errorf("query: encoding cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// String is a function that returns the cursor encoded by Encode, or the empty string when it cannot be encoded
+func (c Cursor) String() string {
+	s, _ := c.Encode()
+	return s
+}
+
+// DecodeCursor is a function that decodes a cursor encoded by Cursor.Encode, the empty string being the
+// cursor of the first page
+func DecodeCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var encoded cursorJSON
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&encoded); err != nil || len(encoded.Values) == 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	c := Cursor{Values: encoded.Values, Backward: encoded.Backward}
+	for i, value := range c.Values {
+		switch v := value.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				c.Values[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				c.Values[i] = f
+			} else {
+				return Cursor{}, ErrInvalidCursor
+			}
+		case map[string]any:
+			x, _ := v["x"].(string)
+			blob, err := hex.DecodeString(x)
+			if err != nil {
+				return Cursor{}, ErrInvalidCursor
+			}
+			c.Values[i] = blob
+		case string, nil:
+		default:
+			return Cursor{}, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+// Page is a function that returns the condition, ORDER BY and LIMIT clauses selecting the page of the keyset
+// at the cursor. The condition is added to the WHERE clause of the query, which must not be grouped, ordered
+// or limited. When paging backward the rows are sorted in reverse, so the page must be reversed after it is
+// read.
+func (q *Query) Page(keyset Keyset, cursor Cursor) *Query {
+	if len(keyset.Keys) == 0 {
+		q.setErr(errors.New("query: keyset without sort keys"))
+		return q
+	}
+	if _, end, _ := q.whereSpan(); end < len(q.query) {
+		q.setErr(errors.New("query: Page on a query that is already grouped, ordered or limited"))
+		return q
+	}
+	if len(cursor.Values) > 0 {
+		if len(cursor.Values) != len(keyset.Keys) {
+			q.setErr(fmt.Errorf("query: cursor has %d values but the keyset %d sort keys", len(cursor.Values), len(keyset.Keys)))
+			return q
+		}
+		q.andWhere(func() { q.keysetCondition(keyset.Keys, cursor) })
+	}
+
+	q.query = append(q.query, " ORDER BY "...)
+	for i, key := range keyset.Keys {
+		if i > 0 {
+			q.query = append(q.query, ", "...)
+		}
+		q.query = append(q.query, key.Column...)
+		if key.Desc != cursor.Backward {
+			q.query = append(q.query, " DESC"...)
+		} else {
+			q.query = append(q.query, " ASC"...)
+		}
+	}
+	if keyset.Limit > 0 {
+		q.Limit(keyset.Limit)
+	}
+	return q
+}
+
+// keysetCondition appends the condition matching the rows after the cursor in the order of the keys, as a
+// row value comparison when the keys are sorted in the same direction
+func (q *Query) keysetCondition(keys []SortKey, cursor Cursor) {
+	op := func(key SortKey) string {
+		if key.Desc != cursor.Backward {
+			return " < "
+		}
+		return " > "
+	}
+
+	sameDirection := true
+	for _, key := range keys[1:] {
+		sameDirection = sameDirection && key.Desc == keys[0].Desc
+	}
+	if sameDirection {
+		columns := make([]string, len(keys))
+		for i, key := range keys {
+			columns[i] = key.Column
+		}
+		if len(keys) == 1 {
+			q.query = append(q.query, keys[0].Column...)
+			q.query = append(q.query, op(keys[0])...)
+			q.query = append(q.query, '?')
+		} else {
+			q.query = append(q.query, '(')
+			q.query = append(q.query, strings.Join(columns, ", ")...)
+			q.query = append(q.query, ')')
+			q.query = append(q.query, op(keys[0])...)
+			q.query = append(q.query, '(')
+			q.query = append(q.query, strings.Repeat(", ?", len(keys))[2:]...)
+			q.query = append(q.query, ')')
+		}
+		q.args = append(q.args, cursor.Values...)
+		return
+	}
+
+	// (a > ? OR (a = ? AND b < ?) OR (a = ? AND b = ? AND c > ?))
+	q.query = append(q.query, '(')
+	for i, key := range keys {
+		if i > 0 {
+			q.query = append(q.query, " OR ("...)
+		}
+		for j := 0; j < i; j++ {
+			q.query = append(q.query, keys[j].Column...)
+			q.query = append(q.query, " = ? AND "...)
+			q.args = append(q.args, cursor.Values[j])
+		}
+		q.query = append(q.query, key.Column...)
+		q.query = append(q.query, op(key)...)
+		q.query = append(q.query, '?')
+		q.args = append(q.args, cursor.Values[i])
+		if i > 0 {
+			q.query = append(q.query, ')')
+		}
+	}
+	q.query = append(q.query, ')')
+}
+
+// whereClauses are the clauses following WHERE, before which andWhere adds its condition
+var whereClauses = []string{"GROUP", "HAVING", "WINDOW", "ORDER", "LIMIT", "RETURNING"}
+
+// whereSpan returns the offsets of the condition of the top-level WHERE clause of the query, start being -1
+// when it has none and end the offset of the clause following it, and whether the condition has an OR
+func (q *Query) whereSpan() (start, end int, or bool) {
+	start, end = -1, len(q.query)
+	depth, offset := 0, 0
+	for _, tok := range tokenize(string(q.query)) {
+		offset += len(tok.text)
+		switch {
+		case tok.text == "(":
+			depth++
+		case tok.text == ")":
+			depth--
+		case depth > 0 || tok.kind != tokenWord:
+		case slices.ContainsFunc(whereClauses, func(clause string) bool { return strings.EqualFold(tok.text, clause) }):
+			return start, offset - len(tok.text), or
+		case start < 0 && strings.EqualFold(tok.text, "WHERE"):
+			start = offset
+		case start >= 0 && strings.EqualFold(tok.text, "OR"):
+			or = true
+		}
+	}
+	return start, end, or
+}
+
+// andWhere adds the condition appended by fn to the WHERE clause of the query, adding the clause when it has
+// none, before its GROUP BY, HAVING, WINDOW, ORDER BY, LIMIT or RETURNING clause. The condition of the clause
+// is put in parentheses when it has an OR.
+func (q *Query) andWhere(fn func()) {
+	start, end, or := q.whereSpan()
+
+	// cut the clauses following the condition and their arguments, restored after it
+	tail := append([]byte(nil), q.query[end:]...)
+	n := min(Placeholders(string(q.query[:end])), len(q.args))
+	tailArgs := append([]any(nil), q.args[n:]...)
+	q.query = bytes.TrimRight(q.query[:end], " \t\r\n")
+	q.args = q.args[:n]
+
+	switch {
+	case start < 0:
+		q.query = append(q.query, " WHERE "...)
+	case or:
+		condition := strings.TrimSpace(string(q.query[start:]))
+		q.query = append(q.query[:start], " ("...)
+		q.query = append(q.query, condition...)
+		q.query = append(q.query, ") AND "...)
+	default:
+		q.query = append(q.query, " AND "...)
+	}
+	fn()
+
+	if len(tail) > 0 {
+		q.query = append(q.query, ' ')
+		q.query = append(q.query, tail...)
+		q.args = append(q.args, tailArgs...)
+	}
+}
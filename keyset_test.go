@@ -0,0 +1,117 @@
+package query_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tinytoolkit/query"
+)
+
+func TestPage(t *testing.T) {
+	byID := query.Keyset{Keys: []query.SortKey{{Column: "id"}}, Limit: 20}
+	byDate := query.Keyset{Keys: []query.SortKey{{Column: "created_at", Desc: true}, {Column: "id", Desc: true}}, Limit: 20}
+	mixed := query.Keyset{Keys: []query.SortKey{{Column: "score", Desc: true}, {Column: "name"}, {Column: "id"}}, Limit: 10}
+
+	tests := []struct {
+		q        *query.Query
+		expected string
+		args     []any
+	}{
+		{
+			query.Select("*").From("posts").Page(byID, query.Cursor{}),
+			"SELECT * FROM posts ORDER BY id ASC LIMIT ?",
+			[]any{20},
+		},
+		{
+			query.Select("*").From("posts").Page(byID, byID.After(int64(40))),
+			"SELECT * FROM posts WHERE id > ? ORDER BY id ASC LIMIT ?",
+			[]any{int64(40), 20},
+		},
+		{
+			query.Select("*").From("posts").Where("author_id = ?").Args(3).Page(byDate, byDate.After("2024-05-06", int64(9))),
+			"SELECT * FROM posts WHERE author_id = ? AND (created_at, id) < (?, ?) ORDER BY created_at DESC, id DESC LIMIT ?",
+			[]any{3, "2024-05-06", int64(9), 20},
+		},
+		{
+			query.Select("*").From("posts").Where("author_id = ? OR public").Args(3).Page(byID, byID.After(int64(40))),
+			"SELECT * FROM posts WHERE (author_id = ? OR public) AND id > ? ORDER BY id ASC LIMIT ?",
+			[]any{3, int64(40), 20},
+		},
+		{
+			query.Select("*").From("posts").Page(byDate, byDate.Before("2024-05-06", int64(9))),
+			"SELECT * FROM posts WHERE (created_at, id) > (?, ?) ORDER BY created_at ASC, id ASC LIMIT ?",
+			[]any{"2024-05-06", int64(9), 20},
+		},
+		{
+			query.Select("*").From("players").Where("id IN (SELECT id FROM teams WHERE active)").Page(mixed, mixed.After(7.5, "bob", int64(2))),
+			"SELECT * FROM players WHERE id IN (SELECT id FROM teams WHERE active) AND " +
+				"(score < ? OR (score = ? AND name > ?) OR (score = ? AND name = ? AND id > ?)) ORDER BY score DESC, name ASC, id ASC LIMIT ?",
+			[]any{7.5, 7.5, "bob", 7.5, "bob", int64(2), 10},
+		},
+		{
+			query.Select("*").From("(SELECT * FROM players WHERE active)").Page(mixed, mixed.Before(7.5, "bob", int64(2))),
+			"SELECT * FROM (SELECT * FROM players WHERE active) WHERE " +
+				"(score > ? OR (score = ? AND name < ?) OR (score = ? AND name = ? AND id < ?)) ORDER BY score ASC, name DESC, id DESC LIMIT ?",
+			[]any{7.5, 7.5, "bob", 7.5, "bob", int64(2), 10},
+		},
+	}
+
+	for _, test := range tests {
+		q, args, err := test.q.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q != test.expected {
+			t.Errorf("Expected query '%s', but got '%s'", test.expected, q)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("Expected args %v, but got %v", test.args, args)
+		}
+	}
+
+	if _, _, err := query.Select("*").From("posts").Page(byDate, byDate.After(1)).Build(); err == nil {
+		t.Error("Expected an error for a cursor missing sort key values")
+	}
+	for _, q := range []*query.Query{
+		query.Select("*").From("posts").OrderBy("title"),
+		query.Select("*").From("posts").Limit(5),
+		query.Select("author_id", "count(*)").From("posts").GroupBy("author_id"),
+	} {
+		if _, _, err := q.Page(byID, byID.After(int64(40))).Build(); err == nil {
+			t.Error("Expected an error for a query already grouped, ordered or limited")
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	cursor := query.Cursor{Values: []any{created, int64(1) << 60, 2.5, "a/b", []byte{0xde, 0xad}, true}, Backward: true}
+	encoded, err := cursor.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := query.DecodeCursor(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := query.Cursor{Values: []any{"2024-05-06 07:08:09.000", int64(1) << 60, 2.5, "a/b", []byte{0xde, 0xad}, int64(1)}, Backward: true}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected cursor %v, but got %v", expected, decoded)
+	}
+
+	if _, err := (query.Cursor{Values: []any{math.NaN()}}).Encode(); err == nil {
+		t.Error("Expected an error for a value JSON cannot encode")
+	}
+	if first, err := query.DecodeCursor(""); err != nil || len(first.Values) != 0 {
+		t.Errorf("Expected the empty cursor to select the first page, but got %v %v", first, err)
+	}
+	for _, invalid := range []string{"!!", "bm90IGpzb24", "eyJ2IjpbXX0"} {
+		if _, err := query.DecodeCursor(invalid); !errors.Is(err, query.ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor for '%s', but got %v", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenKind is the kind of a token of an SQL string
+type tokenKind int
+
+const (
+	tokenSpace tokenKind = iota
+	tokenComment
+	tokenString
+	tokenBlob
+	tokenIdent
+	tokenWord
+	tokenNumber
+	tokenParam
+	tokenPunct
+)
+
+// token is a piece of an SQL string as split by tokenize
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits an SQL string into tokens following the SQLite lexical rules, so that the text of the
+// tokens concatenated is the original string
+func tokenize(sql string) []token {
+	var tokens []token
+	for i := 0; i < len(sql); {
+		kind, n := tokenPunct, 1
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			kind = tokenSpace
+			for n < len(sql)-i && strings.IndexByte(" \t\n\r\f", sql[i+n]) >= 0 {
+				n++
+			}
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			kind = tokenComment
+			if n = strings.IndexByte(sql[i:], '\n'); n < 0 {
+				n = len(sql) - i
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			kind = tokenComment
+			if n = strings.Index(sql[i+2:], "*/"); n < 0 {
+				n = len(sql) - i
+			} else {
+				n += 4
+			}
+		case c == '\'':
+			kind, n = tokenString, quoted(sql[i:], '\'')
+		case (c == 'x' || c == 'X') && i+1 < len(sql) && sql[i+1] == '\'':
+			kind, n = tokenBlob, 1+quoted(sql[i+1:], '\'')
+		case c == '"' || c == '`':
+			kind, n = tokenIdent, quoted(sql[i:], c)
+		case c == '[':
+			kind = tokenIdent
+			if n = strings.IndexByte(sql[i:], ']'); n < 0 {
+				n = len(sql) - i
+			} else {
+				n++
+			}
+		case c == '?':
+			kind = tokenParam
+			for n < len(sql)-i && isDigit(sql[i+n]) {
+				n++
+			}
+		case (c == ':' || c == '@' || c == '$') && i+1 < len(sql) && isWordByte(sql[i+1]):
+			kind = tokenParam
+			for n < len(sql)-i && isWordByte(sql[i+n]) {
+				n++
+			}
+		case isDigit(c) || c == '.' && i+1 < len(sql) && isDigit(sql[i+1]):
+			kind = tokenNumber
+			for n < len(sql)-i && (isWordByte(sql[i+n]) || sql[i+n] == '.' ||
+				(sql[i+n] == '+' || sql[i+n] == '-') && (sql[i+n-1] == 'e' || sql[i+n-1] == 'E')) {
+				n++
+			}
+		case isWordByte(c):
+			kind = tokenWord
+			for n < len(sql)-i && isWordByte(sql[i+n]) {
+				n++
+			}
+		case strings.HasPrefix(sql[i:], "||") || strings.HasPrefix(sql[i:], "<=") || strings.HasPrefix(sql[i:], ">=") ||
+			strings.HasPrefix(sql[i:], "<>") || strings.HasPrefix(sql[i:], "!=") || strings.HasPrefix(sql[i:], "==") ||
+			strings.HasPrefix(sql[i:], "<<") || strings.HasPrefix(sql[i:], ">>") || strings.HasPrefix(sql[i:], "->"):
+			n = 2
+			if strings.HasPrefix(sql[i:], "->>") {
+				n = 3
+			}
+		}
+		tokens = append(tokens, token{kind: kind, text: sql[i : i+n]})
+		i += n
+	}
+	return tokens
+}
+
+// quoted returns the length of the quoted string at the start of s, where doubled quotes are escapes
+func quoted(s string, quote byte) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// Placeholders is a function that returns the number of arguments an SQL string binds, which is the largest
+// number of its parameters as numbered by SQLite. Placeholders in literals, identifiers and comments are ignored.
+func Placeholders(sql string) int {
+	var params parameters
+	for _, tok := range tokenize(sql) {
+		if tok.kind == tokenParam {
+			params.number(tok.text)
+		}
+	}
+	return params.max
+}
+
+// parameters numbers the parameters of an SQL string like SQLite: ? takes the largest number so far plus one,
+// ?NNN the number NNN, and a named parameter the largest number so far plus one on its first occurrence
+type parameters struct {
+	max   int
+	named map[string]int
+}
+
+// number returns the number of the parameter
+func (p *parameters) number(param string) int {
+	n := p.max + 1
+	switch {
+	case param == "?":
+	case param[0] == '?':
+		n, _ = strconv.Atoi(param[1:])
+	default:
+		if i, ok := p.named[param]; ok {
+			return i
+		}
+		if p.named == nil {
+			p.named = map[string]int{}
+		}
+		p.named[param] = n
+	}
+	if n > p.max {
+		p.max = n
+	}
+	return n
+}
+
+// PlaceholderError is an error returned by Build when the placeholders of a query do not match its arguments
+type PlaceholderError struct {
+	Query        string
+	Placeholders int
+	Args         []any
+	// Offset is the position in Query of the first placeholder without an argument, or -1
+	Offset int
+}
+
+// Error is a function that returns the error message pointing at the mismatch
+func (e *PlaceholderError) Error() string {
+	msg := fmt.Sprintf("query: %d placeholders but %d arguments", e.Placeholders, len(e.Args))
+	if e.Offset < 0 {
+		extra := e.Args[e.Placeholders:]
+		literals := make([]string, len(extra))
+		for i, arg := range extra {
+			literals[i] = Literal(arg)
+		}
+		return fmt.Sprintf("%s, no placeholder for %s in %q", msg, strings.Join(literals, ", "), e.Query)
+	}
+
+	end := e.Offset + 1
+	for end < len(e.Query) && isWordByte(e.Query[end]) {
+		end++
+	}
+	context := e.Query[:end]
+	if len(context) > 40 {
+		context = "..." + context[len(context)-40:]
+	}
+	return fmt.Sprintf("%s, no argument for %s at offset %d in %q <-- here", msg, e.Query[e.Offset:end], e.Offset, context)
+}
+
+// checkPlaceholders returns a *PlaceholderError when the placeholders of a query do not match its arguments
+func checkPlaceholders(query string, args []any) error {
+	var (
+		params parameters
+		offset = -1
+		pos    int
+	)
+	for _, tok := range tokenize(query) {
+		if tok.kind == tokenParam && params.number(tok.text) > len(args) && offset < 0 {
+			offset = pos
+		}
+		pos += len(tok.text)
+	}
+	if params.max == len(args) {
+		return nil
+	}
+	return &PlaceholderError{Query: query, Placeholders: params.max, Args: args, Offset: offset}
+}
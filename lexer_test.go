@@ -0,0 +1,60 @@
+package query_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+func TestPlaceholders(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"SELECT 1", 0},
+		{"SELECT * FROM users WHERE id = ? AND name = ?", 2},
+		{"SELECT '?', \"?\", [?], `?` -- ?\n/* ? */ FROM t WHERE a = ?", 1},
+		{"SELECT ?3, ?", 4},
+		{"SELECT ?2, ?1", 2},
+		{"SELECT :a, @b, :a, $c", 3},
+		{"SELECT x'3f', ?", 1},
+	}
+	for _, test := range tests {
+		if n := query.Placeholders(test.query); n != test.expected {
+			t.Errorf("Expected %d placeholders in '%s', but got %d", test.expected, test.query, n)
+		}
+	}
+}
+
+func TestBuildPlaceholders(t *testing.T) {
+	tests := []struct {
+		q        *query.Query
+		expected string
+	}{
+		{
+			query.Select("*").From("users").Where("active = ? AND ").Like("name").Args(true),
+			`query: 2 placeholders but 1 arguments, no argument for ? at offset 51 in "...M users WHERE active = ? AND name LIKE ?" <-- here`,
+		},
+		{
+			query.Select("?3").Args(1, 2),
+			`query: 3 placeholders but 2 arguments, no argument for ?3 at offset 7 in "SELECT ?3" <-- here`,
+		},
+		{
+			query.Select(":id").Args(1, "extra", query.Sensitive("secret")),
+			`query: 1 placeholders but 3 arguments, no placeholder for 'extra', '[REDACTED]' in "SELECT :id"`,
+		},
+	}
+	for _, test := range tests {
+		_, _, err := test.q.Build()
+		var placeholderErr *query.PlaceholderError
+		if !errors.As(err, &placeholderErr) || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', but got '%v'", test.expected, err)
+		}
+	}
+
+	q, args, err := query.Select("*").From("users").Where("name = '?' AND id = ?2 AND team = ?1").Args(1, 2).Build()
+	if err != nil || len(args) != 2 {
+		t.Errorf("Expected query '%s' with 2 arguments, but got %v (%v)", q, args, err)
+	}
+}
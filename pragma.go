@@ -0,0 +1,450 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JournalMode is a value of the journal_mode pragma
+type JournalMode string
+
+// The journal modes supported by SQLite
+const (
+	JournalModeDelete   JournalMode = "DELETE"
+	JournalModeTruncate JournalMode = "TRUNCATE"
+	JournalModePersist  JournalMode = "PERSIST"
+	JournalModeMemory   JournalMode = "MEMORY"
+	JournalModeWAL      JournalMode = "WAL"
+	JournalModeOff      JournalMode = "OFF"
+)
+
+func (m JournalMode) valid() bool {
+	switch m {
+	case JournalModeDelete, JournalModeTruncate, JournalModePersist, JournalModeMemory, JournalModeWAL, JournalModeOff:
+		return true
+	}
+	return false
+}
+
+// Synchronous is a value of the synchronous pragma
+type Synchronous string
+
+// The synchronous settings supported by SQLite, in the order of their numeric values
+const (
+	SynchronousOff    Synchronous = "OFF"
+	SynchronousNormal Synchronous = "NORMAL"
+	SynchronousFull   Synchronous = "FULL"
+	SynchronousExtra  Synchronous = "EXTRA"
+)
+
+var synchronousLevels = []Synchronous{SynchronousOff, SynchronousNormal, SynchronousFull, SynchronousExtra}
+
+func (s Synchronous) valid() bool {
+	for _, level := range synchronousLevels {
+		if s == level {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckpointMode is a mode of the wal_checkpoint pragma
+type CheckpointMode string
+
+// The checkpoint modes supported by SQLite
+const (
+	CheckpointPassive  CheckpointMode = "PASSIVE"
+	CheckpointFull     CheckpointMode = "FULL"
+	CheckpointRestart  CheckpointMode = "RESTART"
+	CheckpointTruncate CheckpointMode = "TRUNCATE"
+)
+
+func (m CheckpointMode) valid() bool {
+	switch m {
+	case CheckpointPassive, CheckpointFull, CheckpointRestart, CheckpointTruncate:
+		return true
+	}
+	return false
+}
+
+// PragmaSchema is a function that returns a PRAGMA query for the specified schema, reading the pragma when value is empty
+func PragmaSchema(schema, name, value string) *Query {
+	return getQuery().PragmaSchema(schema, name, value)
+}
+
+// PragmaSchema is a function that returns a PRAGMA query for the specified schema, reading the pragma when value is empty
+func (q *Query) PragmaSchema(schema, name, value string) *Query {
+	if !q.pragmaName(schema, name) {
+		return q
+	}
+	if value != "" {
+		q.query = append(q.query, " = "...)
+		q.query = append(q.query, value...)
+	}
+	q.query = append(q.query, ";"...)
+	return q
+}
+
+// PragmaCall is a function that returns a PRAGMA query in function-call form for the specified schema
+func PragmaCall(schema, name, arg string) *Query {
+	return getQuery().PragmaCall(schema, name, arg)
+}
+
+// PragmaCall is a function that returns a PRAGMA query in function-call form for the specified schema
+func (q *Query) PragmaCall(schema, name, arg string) *Query {
+	if !q.pragmaName(schema, name) {
+		return q
+	}
+	if arg != "" {
+		q.query = append(q.query, "("...)
+		q.query = append(q.query, arg...)
+		q.query = append(q.query, ")"...)
+	}
+	q.query = append(q.query, ";"...)
+	return q
+}
+
+// pragmaName appends the PRAGMA keyword and the qualified name, reporting false when the name is not an identifier
+func (q *Query) pragmaName(schema, name string) bool {
+	if name == "" || ident(name) != name {
+		q.setErr(fmt.Errorf("query: invalid pragma name %q", name))
+		return false
+	}
+	q.query = append(q.query, "PRAGMA "...)
+	if schema != "" {
+		q.query = append(q.query, ident(schema)...)
+		q.query = append(q.query, "."...)
+	}
+	q.query = append(q.query, name...)
+	return true
+}
+
+// PragmaJournalMode is a function that returns a PRAGMA journal_mode query setting the specified mode
+func PragmaJournalMode(schema string, mode JournalMode) *Query {
+	return getQuery().PragmaJournalMode(schema, mode)
+}
+
+// PragmaJournalMode is a function that returns a PRAGMA journal_mode query setting the specified mode
+func (q *Query) PragmaJournalMode(schema string, mode JournalMode) *Query {
+	if !mode.valid() {
+		q.setErr(fmt.Errorf("query: invalid journal mode %q", mode))
+		return q
+	}
+	return q.PragmaSchema(schema, "journal_mode", string(mode))
+}
+
+// PragmaSynchronous is a function that returns a PRAGMA synchronous query setting the specified level
+func PragmaSynchronous(schema string, level Synchronous) *Query {
+	return getQuery().PragmaSynchronous(schema, level)
+}
+
+// PragmaSynchronous is a function that returns a PRAGMA synchronous query setting the specified level
+func (q *Query) PragmaSynchronous(schema string, level Synchronous) *Query {
+	if !level.valid() {
+		q.setErr(fmt.Errorf("query: invalid synchronous level %q", level))
+		return q
+	}
+	return q.PragmaSchema(schema, "synchronous", string(level))
+}
+
+// PragmaForeignKeys is a function that returns a PRAGMA foreign_keys query enabling or disabling foreign key enforcement
+func PragmaForeignKeys(enabled bool) *Query {
+	return getQuery().PragmaForeignKeys(enabled)
+}
+
+// PragmaForeignKeys is a function that returns a PRAGMA foreign_keys query enabling or disabling foreign key enforcement
+func (q *Query) PragmaForeignKeys(enabled bool) *Query {
+	value := "OFF"
+	if enabled {
+		value = "ON"
+	}
+	return q.PragmaSchema("", "foreign_keys", value)
+}
+
+// PragmaBusyTimeout is a function that returns a PRAGMA busy_timeout query setting the specified timeout
+func PragmaBusyTimeout(timeout time.Duration) *Query {
+	return getQuery().PragmaBusyTimeout(timeout)
+}
+
+// PragmaBusyTimeout is a function that returns a PRAGMA busy_timeout query setting the specified timeout
+func (q *Query) PragmaBusyTimeout(timeout time.Duration) *Query {
+	if timeout < 0 {
+		q.setErr(fmt.Errorf("query: invalid busy timeout %v", timeout))
+		return q
+	}
+	return q.PragmaSchema("", "busy_timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+}
+
+// PragmaCacheSize is a function that returns a PRAGMA cache_size query, in pages when positive and in KiB when negative
+func PragmaCacheSize(schema string, size int) *Query {
+	return getQuery().PragmaCacheSize(schema, size)
+}
+
+// PragmaCacheSize is a function that returns a PRAGMA cache_size query, in pages when positive and in KiB when negative
+func (q *Query) PragmaCacheSize(schema string, size int) *Query {
+	return q.PragmaSchema(schema, "cache_size", strconv.Itoa(size))
+}
+
+// PragmaMmapSize is a function that returns a PRAGMA mmap_size query setting the specified size in bytes
+func PragmaMmapSize(schema string, size int64) *Query {
+	return getQuery().PragmaMmapSize(schema, size)
+}
+
+// PragmaMmapSize is a function that returns a PRAGMA mmap_size query setting the specified size in bytes
+func (q *Query) PragmaMmapSize(schema string, size int64) *Query {
+	if size < 0 {
+		q.setErr(fmt.Errorf("query: invalid mmap size %d", size))
+		return q
+	}
+	return q.PragmaSchema(schema, "mmap_size", strconv.FormatInt(size, 10))
+}
+
+// PragmaWALCheckpoint is a function that returns a PRAGMA wal_checkpoint query with the specified mode
+func PragmaWALCheckpoint(schema string, mode CheckpointMode) *Query {
+	return getQuery().PragmaWALCheckpoint(schema, mode)
+}
+
+// PragmaWALCheckpoint is a function that returns a PRAGMA wal_checkpoint query with the specified mode
+func (q *Query) PragmaWALCheckpoint(schema string, mode CheckpointMode) *Query {
+	if mode != "" && !mode.valid() {
+		q.setErr(fmt.Errorf("query: invalid checkpoint mode %q", mode))
+		return q
+	}
+	return q.PragmaCall(schema, "wal_checkpoint", string(mode))
+}
+
+// PragmaOptimize is a function that returns a PRAGMA optimize query, with the specified mask when not zero
+func PragmaOptimize(schema string, mask uint) *Query {
+	return getQuery().PragmaOptimize(schema, mask)
+}
+
+// PragmaOptimize is a function that returns a PRAGMA optimize query, with the specified mask when not zero
+func (q *Query) PragmaOptimize(schema string, mask uint) *Query {
+	arg := ""
+	if mask != 0 {
+		arg = "0x" + strconv.FormatUint(uint64(mask), 16)
+	}
+	return q.PragmaCall(schema, "optimize", arg)
+}
+
+// PragmaIntegrityCheck is a function that returns a PRAGMA integrity_check query, limited to maxErrors when positive
+func PragmaIntegrityCheck(schema string, maxErrors int) *Query {
+	return getQuery().PragmaIntegrityCheck(schema, maxErrors)
+}
+
+// PragmaIntegrityCheck is a function that returns a PRAGMA integrity_check query, limited to maxErrors when positive
+func (q *Query) PragmaIntegrityCheck(schema string, maxErrors int) *Query {
+	arg := ""
+	if maxErrors > 0 {
+		arg = strconv.Itoa(maxErrors)
+	}
+	return q.PragmaCall(schema, "integrity_check", arg)
+}
+
+// PragmaUserVersion is a function that returns a PRAGMA user_version query setting the specified version
+func PragmaUserVersion(schema string, version int32) *Query {
+	return getQuery().PragmaUserVersion(schema, version)
+}
+
+// PragmaUserVersion is a function that returns a PRAGMA user_version query setting the specified version
+func (q *Query) PragmaUserVersion(schema string, version int32) *Query {
+	return q.PragmaSchema(schema, "user_version", strconv.FormatInt(int64(version), 10))
+}
+
+// PragmaApplicationID is a function that returns a PRAGMA application_id query setting the specified id
+func PragmaApplicationID(schema string, id int32) *Query {
+	return getQuery().PragmaApplicationID(schema, id)
+}
+
+// PragmaApplicationID is a function that returns a PRAGMA application_id query setting the specified id
+func (q *Query) PragmaApplicationID(schema string, id int32) *Query {
+	return q.PragmaSchema(schema, "application_id", strconv.FormatInt(int64(id), 10))
+}
+
+// Pragmas is a struct that runs typed PRAGMA queries on a database and parses their results.
+// Connection-scoped pragmas such as foreign_keys and busy_timeout only apply to the connection
+// that happens to run them when DB is a pool.
+type Pragmas struct {
+	DB     Executor
+	Schema string
+}
+
+// JournalMode is a function that returns the journal mode
+func (p Pragmas) JournalMode(ctx context.Context) (JournalMode, error) {
+	var mode string
+	err := p.scan(ctx, PragmaSchema(p.Schema, "journal_mode", ""), &mode)
+	return JournalMode(strings.ToUpper(mode)), err
+}
+
+// SetJournalMode is a function that sets the journal mode and returns the mode in effect, which can differ for in-memory databases
+func (p Pragmas) SetJournalMode(ctx context.Context, mode JournalMode) (JournalMode, error) {
+	var current string
+	err := p.scan(ctx, PragmaJournalMode(p.Schema, mode), &current)
+	return JournalMode(strings.ToUpper(current)), err
+}
+
+// Synchronous is a function that returns the synchronous level
+func (p Pragmas) Synchronous(ctx context.Context) (Synchronous, error) {
+	var level int
+	if err := p.scan(ctx, PragmaSchema(p.Schema, "synchronous", ""), &level); err != nil {
+		return "", err
+	}
+	if level < 0 || level >= len(synchronousLevels) {
+		return "", fmt.Errorf("query: unknown synchronous level %d", level)
+	}
+	return synchronousLevels[level], nil
+}
+
+// SetSynchronous is a function that sets the synchronous level
+func (p Pragmas) SetSynchronous(ctx context.Context, level Synchronous) error {
+	_, err := Exec(ctx, p.DB, PragmaSynchronous(p.Schema, level))
+	return err
+}
+
+// ForeignKeys is a function that reports whether foreign key enforcement is enabled
+func (p Pragmas) ForeignKeys(ctx context.Context) (bool, error) {
+	var enabled bool
+	err := p.scan(ctx, PragmaSchema("", "foreign_keys", ""), &enabled)
+	return enabled, err
+}
+
+// SetForeignKeys is a function that enables or disables foreign key enforcement
+func (p Pragmas) SetForeignKeys(ctx context.Context, enabled bool) error {
+	_, err := Exec(ctx, p.DB, PragmaForeignKeys(enabled))
+	return err
+}
+
+// BusyTimeout is a function that returns the busy timeout
+func (p Pragmas) BusyTimeout(ctx context.Context) (time.Duration, error) {
+	var ms int64
+	err := p.scan(ctx, PragmaSchema("", "busy_timeout", ""), &ms)
+	return time.Duration(ms) * time.Millisecond, err
+}
+
+// SetBusyTimeout is a function that sets the busy timeout
+func (p Pragmas) SetBusyTimeout(ctx context.Context, timeout time.Duration) error {
+	var ms int64
+	return p.scan(ctx, PragmaBusyTimeout(timeout), &ms)
+}
+
+// CacheSize is a function that returns the cache size, in pages when positive and in KiB when negative
+func (p Pragmas) CacheSize(ctx context.Context) (int, error) {
+	var size int
+	err := p.scan(ctx, PragmaSchema(p.Schema, "cache_size", ""), &size)
+	return size, err
+}
+
+// SetCacheSize is a function that sets the cache size, in pages when positive and in KiB when negative
+func (p Pragmas) SetCacheSize(ctx context.Context, size int) error {
+	_, err := Exec(ctx, p.DB, PragmaCacheSize(p.Schema, size))
+	return err
+}
+
+// MmapSize is a function that returns the memory-mapped I/O size in bytes
+func (p Pragmas) MmapSize(ctx context.Context) (int64, error) {
+	var size int64
+	err := p.scan(ctx, PragmaSchema(p.Schema, "mmap_size", ""), &size)
+	return size, err
+}
+
+// SetMmapSize is a function that sets the memory-mapped I/O size and returns the size in effect
+func (p Pragmas) SetMmapSize(ctx context.Context, size int64) (int64, error) {
+	var current int64
+	err := p.scan(ctx, PragmaMmapSize(p.Schema, size), &current)
+	return current, err
+}
+
+// CheckpointResult is a struct holding the result of a wal_checkpoint pragma
+type CheckpointResult struct {
+	Busy         bool
+	Log          int
+	Checkpointed int
+}
+
+// WALCheckpoint is a function that runs a checkpoint with the specified mode
+func (p Pragmas) WALCheckpoint(ctx context.Context, mode CheckpointMode) (CheckpointResult, error) {
+	var result CheckpointResult
+	err := p.scan(ctx, PragmaWALCheckpoint(p.Schema, mode), &result.Busy, &result.Log, &result.Checkpointed)
+	return result, err
+}
+
+// Optimize is a function that runs PRAGMA optimize, with the specified mask when not zero
+func (p Pragmas) Optimize(ctx context.Context, mask uint) error {
+	rows, err := QueryRows(ctx, p.DB, PragmaOptimize(p.Schema, mask))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// IntegrityCheck is a function that runs PRAGMA integrity_check and returns the problems found, none when the database is ok
+func (p Pragmas) IntegrityCheck(ctx context.Context, maxErrors int) ([]string, error) {
+	rows, err := QueryRows(ctx, p.DB, PragmaIntegrityCheck(p.Schema, maxErrors))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var problem string
+		if err := rows.Scan(&problem); err != nil {
+			return nil, err
+		}
+		if problem != "ok" {
+			problems = append(problems, problem)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// UserVersion is a function that returns the user version
+func (p Pragmas) UserVersion(ctx context.Context) (int32, error) {
+	var version int32
+	err := p.scan(ctx, PragmaSchema(p.Schema, "user_version", ""), &version)
+	return version, err
+}
+
+// SetUserVersion is a function that sets the user version
+func (p Pragmas) SetUserVersion(ctx context.Context, version int32) error {
+	_, err := Exec(ctx, p.DB, PragmaUserVersion(p.Schema, version))
+	return err
+}
+
+// ApplicationID is a function that returns the application id
+func (p Pragmas) ApplicationID(ctx context.Context) (int32, error) {
+	var id int32
+	err := p.scan(ctx, PragmaSchema(p.Schema, "application_id", ""), &id)
+	return id, err
+}
+
+// SetApplicationID is a function that sets the application id
+func (p Pragmas) SetApplicationID(ctx context.Context, id int32) error {
+	_, err := Exec(ctx, p.DB, PragmaApplicationID(p.Schema, id))
+	return err
+}
+
+// scan runs a pragma and scans the first row of its result into dest
+func (p Pragmas) scan(ctx context.Context, q *Query, dest ...any) error {
+	rows, err := QueryRows(ctx, p.DB, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("query: pragma returned no rows")
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
@@ -0,0 +1,124 @@
+package query_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+func TestPragmaBuilders(t *testing.T) {
+	tests := []struct {
+		q        *query.Query
+		expected string
+	}{
+		{query.PragmaSchema("main", "journal_mode", ""), "PRAGMA main.journal_mode;"},
+		{query.PragmaSchema("my db", "user_version", "3"), `PRAGMA "my db".user_version = 3;`},
+		{query.PragmaCall("", "table_info", "'foo'"), "PRAGMA table_info('foo');"},
+		{query.PragmaJournalMode("", query.JournalModeWAL), "PRAGMA journal_mode = WAL;"},
+		{query.PragmaSynchronous("main", query.SynchronousNormal), "PRAGMA main.synchronous = NORMAL;"},
+		{query.PragmaForeignKeys(true), "PRAGMA foreign_keys = ON;"},
+		{query.PragmaBusyTimeout(5 * time.Second), "PRAGMA busy_timeout = 5000;"},
+		{query.PragmaCacheSize("", -2000), "PRAGMA cache_size = -2000;"},
+		{query.PragmaMmapSize("", 1<<28), "PRAGMA mmap_size = 268435456;"},
+		{query.PragmaWALCheckpoint("", query.CheckpointTruncate), "PRAGMA wal_checkpoint(TRUNCATE);"},
+		{query.PragmaOptimize("", 0), "PRAGMA optimize;"},
+		{query.PragmaOptimize("", 0x10002), "PRAGMA optimize(0x10002);"},
+		{query.PragmaIntegrityCheck("", 10), "PRAGMA integrity_check(10);"},
+		{query.PragmaUserVersion("", 7), "PRAGMA user_version = 7;"},
+		{query.PragmaApplicationID("", 0x0f), "PRAGMA application_id = 15;"},
+	}
+	for _, test := range tests {
+		q := test.q.String()
+		if q != test.expected {
+			t.Errorf("Expected query '%s', but got '%s'", test.expected, q)
+		}
+	}
+}
+
+func TestPragmaValidation(t *testing.T) {
+	if _, _, err := query.PragmaJournalMode("", "BOGUS").Build(); err == nil {
+		t.Errorf("Expected an error for an invalid journal mode")
+	}
+	if _, _, err := query.PragmaSynchronous("", "sometimes").Build(); err == nil {
+		t.Errorf("Expected an error for an invalid synchronous level")
+	}
+	if _, _, err := query.PragmaWALCheckpoint("", "NOW").Build(); err == nil {
+		t.Errorf("Expected an error for an invalid checkpoint mode")
+	}
+	if q := query.PragmaBusyTimeout(-5 * time.Millisecond).String(); q != "" {
+		t.Errorf("Expected no query for a negative busy timeout, but got '%s'", q)
+	}
+	if q := query.PragmaMmapSize("", -1).String(); q != "" {
+		t.Errorf("Expected no query for a negative mmap size, but got '%s'", q)
+	}
+	if q := query.PragmaJournalMode("", "WAL; DROP TABLE users").String(); q != "" {
+		t.Errorf("Expected no query for an invalid journal mode, but got '%s'", q)
+	}
+	if _, _, err := query.PragmaSchema("", "user_version; DROP TABLE users", "").Build(); err == nil {
+		t.Errorf("Expected an error for an invalid pragma name")
+	}
+	if q := query.PragmaCall("main", "table_info(users); DROP TABLE users; --", "").String(); q != "" {
+		t.Errorf("Expected no query for an invalid pragma name, but got '%s'", q)
+	}
+
+	q, _, err := query.PragmaJournalMode("", query.JournalModeWAL).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q != "PRAGMA journal_mode = WAL;" {
+		t.Errorf("Expected query 'PRAGMA journal_mode = WAL;', but got '%s'", q)
+	}
+}
+
+func TestPragmas(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("PRAGMA journal_mode = WAL;").WillReturnRows([]string{"journal_mode"}, []any{"wal"})
+	mock.Expect("PRAGMA synchronous;").WillReturnRows([]string{"synchronous"}, []any{1})
+	mock.Expect("PRAGMA busy_timeout;").WillReturnRows([]string{"timeout"}, []any{5000})
+	mock.Expect("PRAGMA foreign_keys;").WillReturnRows([]string{"foreign_keys"}, []any{1})
+	mock.Expect("PRAGMA wal_checkpoint(PASSIVE);").WillReturnRows([]string{"busy", "log", "checkpointed"}, []any{0, 12, 10})
+	mock.Expect("PRAGMA integrity_check;").WillReturnRows([]string{"integrity_check"}, []any{"row 1 missing from index i"})
+	mock.Expect("PRAGMA user_version;").WillReturnRows([]string{"user_version"}, []any{4})
+	mock.Expect("PRAGMA user_version = 5;")
+	ctx := context.Background()
+	p := query.Pragmas{DB: db}
+
+	mode, err := p.SetJournalMode(ctx, query.JournalModeWAL)
+	if err != nil || mode != query.JournalModeWAL {
+		t.Errorf("Expected journal mode WAL, but got %q (%v)", mode, err)
+	}
+	level, err := p.Synchronous(ctx)
+	if err != nil || level != query.SynchronousNormal {
+		t.Errorf("Expected synchronous NORMAL, but got %q (%v)", level, err)
+	}
+	timeout, err := p.BusyTimeout(ctx)
+	if err != nil || timeout != 5*time.Second {
+		t.Errorf("Expected busy timeout 5s, but got %v (%v)", timeout, err)
+	}
+	enabled, err := p.ForeignKeys(ctx)
+	if err != nil || !enabled {
+		t.Errorf("Expected foreign keys enabled, but got %v (%v)", enabled, err)
+	}
+	result, err := p.WALCheckpoint(ctx, query.CheckpointPassive)
+	if err != nil || result != (query.CheckpointResult{Log: 12, Checkpointed: 10}) {
+		t.Errorf("Expected checkpoint result {false 12 10}, but got %v (%v)", result, err)
+	}
+	problems, err := p.IntegrityCheck(ctx, 0)
+	if err != nil || !reflect.DeepEqual(problems, []string{"row 1 missing from index i"}) {
+		t.Errorf("Expected one integrity problem, but got %v (%v)", problems, err)
+	}
+	version, err := p.UserVersion(ctx)
+	if err != nil || version != 4 {
+		t.Errorf("Expected user version 4, but got %d (%v)", version, err)
+	}
+	if err := p.SetUserVersion(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.SetJournalMode(ctx, "BOGUS"); err == nil {
+		t.Errorf("Expected an error for an invalid journal mode")
+	}
+}
@@ -9,6 +9,7 @@ import (
 type Query struct {
 	query []byte
 	args  []any
+	err   error
 }
 
 // Analyze is a function that returns an ANALYZE query
@@ -182,53 +183,82 @@ func (q *Query) CreateTable(tableName string, columns []Column, options ...strin
 		if i > 0 {
 			q.query = append(q.query, ", "...)
 		}
-		q.query = append(q.query, column.Name...)
+		q.column(column)
+	}
+	q.query = append(q.query, ")"...)
+	if len(options) > 0 {
 		q.query = append(q.query, " "...)
-		q.query = append(q.query, column.Type...)
-		if column.PrimaryKey {
-			q.query = append(q.query, " PRIMARY KEY"...)
-			if column.AutoIncrement {
-				q.query = append(q.query, " AUTOINCREMENT"...)
-			}
+		q.query = append(q.query, strings.Join(options, " ")...)
+	}
+	q.query = append(q.query, ";"...)
+	return q
+}
+
+// CreateTableFrom is a function that returns a CREATE TABLE query for the specified table, followed by a CREATE INDEX query for each of its indexes
+func CreateTableFrom(table Table) *Query {
+	return getQuery().CreateTableFrom(table)
+}
+
+// CreateTableFrom is a function that returns a CREATE TABLE query for the specified table, followed by a CREATE INDEX query for each of its indexes
+func (q *Query) CreateTableFrom(table Table) *Query {
+	q.query = append(q.query, "CREATE TABLE "...)
+	q.query = append(q.query, table.Name...)
+	q.query = append(q.query, " ("...)
+	for i, column := range table.Columns {
+		if i > 0 {
+			q.query = append(q.query, ", "...)
 		}
-		if column.Unique {
-			q.query = append(q.query, " UNIQUE"...)
+		if len(table.PrimaryKey) > 1 {
+			column.PrimaryKey = false
 		}
-		if column.NotNull {
-			q.query = append(q.query, " NOT NULL"...)
+		q.column(column)
+	}
+	if len(table.PrimaryKey) > 1 {
+		q.query = append(q.query, ", PRIMARY KEY ("...)
+		q.query = append(q.query, strings.Join(table.PrimaryKey, ", ")...)
+		q.query = append(q.query, ")"...)
+	}
+	for _, columns := range table.Unique {
+		q.query = append(q.query, ", UNIQUE ("...)
+		q.query = append(q.query, strings.Join(columns, ", ")...)
+		q.query = append(q.query, ")"...)
+	}
+	for _, fk := range table.ForeignKeys {
+		if len(fk.From) < 2 {
+			continue
 		}
-		if column.Check != "" {
-			q.query = append(q.query, " CHECK ("...)
-			q.query = append(q.query, column.Check...)
+		q.query = append(q.query, ", FOREIGN KEY ("...)
+		q.query = append(q.query, strings.Join(fk.From, ", ")...)
+		q.query = append(q.query, ") REFERENCES "...)
+		q.query = append(q.query, fk.Table...)
+		if len(fk.To) > 0 && fk.To[0] != "" {
+			q.query = append(q.query, " ("...)
+			q.query = append(q.query, strings.Join(fk.To, ", ")...)
 			q.query = append(q.query, ")"...)
 		}
-		if column.Default != "" {
-			q.query = append(q.query, " DEFAULT "...)
-			q.query = append(q.query, column.Default...)
-		}
-		if column.Collate != "" {
-			q.query = append(q.query, " COLLATE "...)
-			q.query = append(q.query, column.Collate...)
+		if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+			q.query = append(q.query, " ON UPDATE "...)
+			q.query = append(q.query, fk.OnUpdate...)
 		}
-		if column.References != "" {
-			q.query = append(q.query, " REFERENCES "...)
-			q.query = append(q.query, column.References...)
-			if column.OnUpdate != "" {
-				q.query = append(q.query, " ON UPDATE "...)
-				q.query = append(q.query, column.OnUpdate...)
-			}
-			if column.OnDelete != "" {
-				q.query = append(q.query, " ON DELETE "...)
-				q.query = append(q.query, column.OnDelete...)
-			}
+		if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+			q.query = append(q.query, " ON DELETE "...)
+			q.query = append(q.query, fk.OnDelete...)
 		}
 	}
 	q.query = append(q.query, ")"...)
-	if len(options) > 0 {
-		q.query = append(q.query, " "...)
-		q.query = append(q.query, strings.Join(options, " ")...)
+	if table.WithoutRowID {
+		q.query = append(q.query, " WITHOUT ROWID"...)
+	}
+	if table.Strict {
+		if table.WithoutRowID {
+			q.query = append(q.query, ","...)
+		}
+		q.query = append(q.query, " STRICT"...)
 	}
 	q.query = append(q.query, ";"...)
+	for _, index := range table.Indexes {
+		q.CreateIndex(index.Name, table.Name, index.Columns, index.Unique)
+	}
 	return q
 }
 
@@ -278,6 +308,25 @@ func (q *Query) RenameColumn(oldName, newName string) *Query {
 // AddColumn is a function that returns an ADD COLUMN query
 func (q *Query) AddColumn(column Column, options ...string) *Query {
 	q.query = append(q.query, " ADD COLUMN "...)
+	q.column(column)
+	if len(options) > 0 {
+		q.query = append(q.query, " "...)
+		q.query = append(q.query, strings.Join(options, " ")...)
+	}
+	q.query = append(q.query, ";"...)
+	return q
+}
+
+// DropColumn is a function that returns a DROP COLUMN query
+func (q *Query) DropColumn(columnName string) *Query {
+	q.query = append(q.query, " DROP COLUMN "...)
+	q.query = append(q.query, columnName...)
+	q.query = append(q.query, ";"...)
+	return q
+}
+
+// column builds the definition of a column in a CREATE TABLE or ADD COLUMN statement
+func (q *Query) column(column Column) {
 	q.query = append(q.query, column.Name...)
 	q.query = append(q.query, " "...)
 	q.query = append(q.query, column.Type...)
@@ -318,20 +367,6 @@ func (q *Query) AddColumn(column Column, options ...string) *Query {
 			q.query = append(q.query, column.OnDelete...)
 		}
 	}
-	if len(options) > 0 {
-		q.query = append(q.query, " "...)
-		q.query = append(q.query, strings.Join(options, " ")...)
-	}
-	q.query = append(q.query, ";"...)
-	return q
-}
-
-// DropColumn is a function that returns a DROP COLUMN query
-func (q *Query) DropColumn(columnName string) *Query {
-	q.query = append(q.query, " DROP COLUMN "...)
-	q.query = append(q.query, columnName...)
-	q.query = append(q.query, ";"...)
-	return q
 }
 
 // CreateIndex is a function that returns a CREATE INDEX query for the specified index and columns
@@ -467,7 +502,7 @@ func (q *Query) Columns(columns ...string) *Query {
 	return q
 }
 
-// Values builds the query string for the VALUES clause in an INSERT INTO statement
+// Values builds the query string for the VALUES clause in an INSERT INTO statement, inlining Expr values
 func (q *Query) Values(values ...any) *Query {
 	q.query = append(q.query, " VALUES"...)
 	q.query = append(q.query, " ("...)
@@ -475,10 +510,9 @@ func (q *Query) Values(values ...any) *Query {
 		if i > 0 {
 			q.query = append(q.query, ", "...)
 		}
-		q.query = append(q.query, '?')
+		q.value(values[i])
 	}
 	q.query = append(q.query, ')')
-	q.args = append(q.args, values...)
 	return q
 }
 
@@ -529,20 +563,30 @@ type Field struct {
 	Value any
 }
 
-// Set is a function that returns a SET clause for the specified fields
+// Set is a function that returns a SET clause for the specified fields, inlining Expr values
 func (q *Query) Set(fields []*Field) *Query {
 	q.query = append(q.query, " SET "...)
 	for i, field := range fields {
 		q.query = append(q.query, field.Name...)
-		q.query = append(q.query, " = ?"...)
+		q.query = append(q.query, " = "...)
+		q.value(field.Value)
 		if i < len(fields)-1 {
 			q.query = append(q.query, ", "...)
 		}
-		q.args = append(q.args, field.Value)
 	}
 	return q
 }
 
+// value appends an Expr as is and any other value as a placeholder bound to it
+func (q *Query) value(value any) {
+	if expr, ok := value.(Expr); ok {
+		q.expr(expr)
+		return
+	}
+	q.query = append(q.query, '?')
+	q.args = append(q.args, value)
+}
+
 // Select is a function to start building a SELECT query statement
 func Select(conditions ...string) *Query {
 	return getQuery().Select(conditions...)
@@ -680,7 +724,8 @@ func (q *Query) Offset(offset int) *Query {
 	return q
 }
 
-// Paginate is a function that returns a LIMIT and OFFSET clause for the specified page and page size
+// Paginate is a function that returns a LIMIT and OFFSET clause for the specified page and page size.
+// Use Page with a Keyset to page through large tables without OFFSET.
 func (q *Query) Paginate(page, pageSize int) *Query {
 	if page < 1 {
 		page = 1
@@ -830,10 +875,44 @@ func (q *Query) Query() (string, []any) {
 	return query, args
 }
 
+// Build is a function that returns the query string and arguments, or the first error recorded while building the query.
+// It returns a *PlaceholderError when the number of arguments differs from the number of placeholders.
+// The arguments are converted with DefaultConverters.
+func (q *Query) Build() (string, []any, error) {
+	if err := q.err; err != nil {
+		q.Reset()
+		return "", nil, err
+	}
+	query := string(q.query)
+	args := append([]any(nil), q.args...)
+
+	q.Reset()
+	if err := checkPlaceholders(query, args); err != nil {
+		return "", nil, err
+	}
+	if err := DefaultConverters.convertArgs(args); err != nil {
+		return "", nil, err
+	}
+	return query, args, nil
+}
+
+// Err is a function that returns the first error recorded while building the query
+func (q *Query) Err() error {
+	return q.err
+}
+
+// setErr records the first error found while building the query
+func (q *Query) setErr(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
 // Reset is a function that resets the query string and arguments
 func (q *Query) Reset() {
 	q.query = q.query[:0]
 	q.args = q.args[:0]
+	q.err = nil
 	queryPool.Put(q)
 }
 
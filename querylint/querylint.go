@@ -0,0 +1,230 @@
+// Package querylint provides an analyzer reporting misuses of the query builder
+package querylint
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/tinytoolkit/query"
+)
+
+const queryPath = "github.com/tinytoolkit/query"
+
+// Analyzer is an analyzer reporting queries used after they were returned to the pool, non-constant strings
+// passed to Where, Having and Raw, and Raw calls whose placeholders do not match their arguments
+var Analyzer = &analysis.Analyzer{
+	Name:     "querylint",
+	Doc:      "report misuses of the github.com/tinytoolkit/query builder",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	// the builder itself assembles SQL from identifiers and owns the pool
+	if pass.Pkg.Path() == queryPath {
+		return nil, nil
+	}
+	in := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	in.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		checkCall(pass, n.(*ast.CallExpr))
+	})
+	in.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(n ast.Node) {
+		if body := n.(*ast.FuncDecl).Body; body != nil {
+			r := &reuse{pass: pass, released: map[types.Object]string{}}
+			r.walk(body)
+		}
+	})
+	return nil, nil
+}
+
+// checkCall reports the unsafe arguments of Where, Having and Raw
+func checkCall(pass *analysis.Pass, call *ast.CallExpr) {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || !isQueryMethod(fn) || len(call.Args) == 0 {
+		return
+	}
+	switch fn.Name() {
+	case "Where", "Having", "Raw":
+	default:
+		return
+	}
+
+	value := pass.TypesInfo.Types[call.Args[0]].Value
+	if value == nil || value.Kind() != constant.String {
+		pass.Reportf(call.Args[0].Pos(), "non-constant string passed to %s: bind values with placeholders and arguments instead", fn.Name())
+		return
+	}
+	if fn.Name() == "Raw" && call.Ellipsis == token.NoPos {
+		placeholders, args := query.Placeholders(constant.StringVal(value)), len(call.Args)-1
+		if placeholders != args {
+			pass.Reportf(call.Pos(), "Raw query has %d placeholders but %d arguments", placeholders, args)
+		}
+	}
+}
+
+// reuse finds the queries used after a call returned them to the pool, in the order of the source
+type reuse struct {
+	pass     *analysis.Pass
+	released map[types.Object]string
+}
+
+func (r *reuse) walk(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.DeferStmt, *ast.GoStmt:
+			return false
+		case *ast.BlockStmt:
+			// a query released in a block leaving the function or loop is not released after it
+			before := make(map[types.Object]string, len(r.released))
+			for obj, name := range r.released {
+				before[obj] = name
+			}
+			for _, stmt := range n.List {
+				r.walk(stmt)
+			}
+			if len(n.List) > 0 && terminates(n.List[len(n.List)-1]) {
+				r.released = before
+			}
+			return false
+		case *ast.AssignStmt:
+			for _, rhs := range n.Rhs {
+				r.walk(rhs)
+			}
+			for _, lhs := range n.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok {
+					delete(r.released, r.pass.TypesInfo.ObjectOf(id))
+				} else {
+					r.walk(lhs)
+				}
+			}
+			return false
+		case *ast.CallExpr:
+			released, name := r.releases(n)
+			if released == nil {
+				return true
+			}
+			r.walk(n.Fun)
+			for _, arg := range n.Args {
+				r.walk(arg)
+			}
+			r.released[r.pass.TypesInfo.ObjectOf(released)] = name
+			return false
+		case *ast.Ident:
+			obj := r.pass.TypesInfo.ObjectOf(n)
+			if name, ok := r.released[obj]; ok {
+				r.pass.Reportf(n.Pos(), "%s used after %s returned it to the pool", n.Name, name)
+				delete(r.released, obj)
+			}
+		}
+		return true
+	})
+}
+
+// releases returns the query variable a call returns to the pool and the name of the function doing it
+func (r *reuse) releases(call *ast.CallExpr) (*ast.Ident, string) {
+	fn, ok := typeutil.Callee(r.pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || (fn.Pkg().Path() != queryPath && !strings.HasPrefix(fn.Pkg().Path(), queryPath+"/")) {
+		return nil, ""
+	}
+
+	if isQueryMethod(fn) {
+		switch fn.Name() {
+		case "String", "Query", "Build", "Reset":
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+				if id, ok := astutil.Unparen(sel.X).(*ast.Ident); ok && isQueryVar(r.pass, id) {
+					return id, fn.Name()
+				}
+			}
+		}
+		return nil, ""
+	}
+
+	if !releasing[funcName(fn)] {
+		return nil, ""
+	}
+	for _, arg := range call.Args {
+		if id, ok := astutil.Unparen(arg).(*ast.Ident); ok && isQueryVar(r.pass, id) {
+			return id, fn.Name()
+		}
+	}
+	return nil, ""
+}
+
+// releasing holds the functions and methods of the package and its subpackages that build the queries they
+// are passed, other functions such as With only copy them
+var releasing = map[string]bool{
+	"query.Exec":               true,
+	"query.QueryRows":          true,
+	"query.Script":             true,
+	"query.NewConnector":       true,
+	"query.NewDriverConnector": true,
+	"query.Tx.Exec":            true,
+	"query.Tx.Query":           true,
+	"query.Writer.Exec":        true,
+	"query.Writer.Query":       true,
+	"querytest.Golden":         true,
+}
+
+// funcName returns the name of a function prefixed with the name of its package, and with the name of its
+// receiver type for a method
+func funcName(fn *types.Func) string {
+	name := fn.Pkg().Name() + "."
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return name + fn.Name()
+	}
+	recv := sig.Recv().Type()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	if named, ok := recv.(*types.Named); ok {
+		return name + named.Obj().Name() + "." + fn.Name()
+	}
+	return name + fn.Name()
+}
+
+// terminates reports whether a statement leaves its block
+func terminates(stmt ast.Stmt) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	case *ast.ExprStmt:
+		if call, ok := s.X.(*ast.CallExpr); ok {
+			if id, ok := call.Fun.(*ast.Ident); ok && id.Name == "panic" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isQueryMethod reports whether fn is a method of *query.Query
+func isQueryMethod(fn *types.Func) bool {
+	sig, ok := fn.Type().(*types.Signature)
+	return ok && sig.Recv() != nil && isQuery(sig.Recv().Type())
+}
+
+// isQueryVar reports whether id is a local variable holding a *query.Query
+func isQueryVar(pass *analysis.Pass, id *ast.Ident) bool {
+	v, ok := pass.TypesInfo.ObjectOf(id).(*types.Var)
+	return ok && !v.IsField() && v.Parent() != v.Pkg().Scope() && isQuery(v.Type())
+}
+
+// isQuery reports whether t is *query.Query
+func isQuery(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	return ok && named.Obj().Name() == "Query" && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == queryPath
+}
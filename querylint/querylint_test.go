@@ -0,0 +1,13 @@
+package querylint_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/tinytoolkit/query/querylint"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), querylint.Analyzer, "a")
+}
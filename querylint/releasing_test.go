@@ -0,0 +1,83 @@
+package querylint
+
+import (
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// copying holds the functions of the package that take queries without building them
+var copying = map[string]bool{
+	"query.With": true,
+}
+
+// TestReleasing checks the releasing functions against the API of the real packages: every listed function
+// must exist and take a *query.Query, and every function taking one must be listed or known to copy it
+func TestReleasing(t *testing.T) {
+	// the packages are type-checked from source, the export data of the toolchain may be newer than the loader
+	mode := packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedImports | packages.NeedDeps
+	pkgs, err := packages.Load(&packages.Config{Mode: mode}, queryPath, queryPath+"/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := map[string]bool{}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			t.Fatalf("loading %s: %v", pkg.PkgPath, pkg.Errors)
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			if !obj.Exported() {
+				continue
+			}
+			var funcs []*types.Func
+			switch obj := obj.(type) {
+			case *types.Func:
+				funcs = append(funcs, obj)
+			case *types.TypeName:
+				named, ok := obj.Type().(*types.Named)
+				if !ok || (obj.Name() == "Query" && obj.Pkg().Path() == queryPath) {
+					continue
+				}
+				for i := 0; i < named.NumMethods(); i++ {
+					if named.Method(i).Exported() {
+						funcs = append(funcs, named.Method(i))
+					}
+				}
+			}
+			for _, fn := range funcs {
+				if !takesQuery(fn) {
+					continue
+				}
+				name := funcName(fn)
+				found[name] = true
+				if !releasing[name] && !copying[name] {
+					t.Errorf("%s takes a *query.Query but is neither listed as releasing nor copying it", name)
+				}
+			}
+		}
+	}
+	for name := range releasing {
+		if !found[name] {
+			t.Errorf("%s is listed as releasing but no such function takes a *query.Query", name)
+		}
+	}
+}
+
+// takesQuery reports whether a function has a *query.Query parameter, variadic ones included
+func takesQuery(fn *types.Func) bool {
+	params := fn.Type().(*types.Signature).Params()
+	for i := 0; i < params.Len(); i++ {
+		t := params.At(i).Type()
+		if slice, ok := t.(*types.Slice); ok {
+			t = slice.Elem()
+		}
+		if isQuery(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package a
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+const byName = "name = ?"
+
+func where(column, value string) string {
+	q := query.Select("*").From("users").Where(byName).Args(value)
+	_ = query.Select("*").From("users").Where(column + " = ?")        // want `non-constant string passed to Where`
+	_ = query.Select("*").Having(fmt.Sprintf("count(*) > %s", value)) // want `non-constant string passed to Having`
+	return q.String()
+}
+
+func raw(id int, ids []any) {
+	_ = query.Select().Raw("SELECT * FROM users WHERE id = ? AND name = :name", id, "x")
+	_ = query.Select().Raw("SELECT * FROM users WHERE id = ?2 AND name = '?'", id) // want `Raw query has 2 placeholders but 1 arguments`
+	_ = query.Select().Raw("SELECT ? -- ?", id, id)                                // want `Raw query has 1 placeholders but 2 arguments`
+	_ = query.Select().Raw("SELECT * FROM users WHERE id IN (?, ?)", ids...)
+}
+
+func reuse(ctx context.Context, db query.Executor) {
+	q := query.Select("*").From("users")
+	s := q.String()
+	q.Where("id = 1") // want `q used after String returned it to the pool`
+	_ = s
+
+	q = query.Select("*").From("teams")
+	query.Exec(ctx, db, q)
+	_, _ = q.Query() // want `q used after Exec returned it to the pool`
+
+	sub := query.Select("id").From("admins")
+	w := query.With("admins", sub).Raw("SELECT * FROM admins")
+	_ = sub.String()
+	_ = w.String()
+
+	t := query.Select("*").From("posts")
+	var tx *query.Tx
+	tx.Exec(t)
+	_ = t.String() // want `t used after Exec returned it to the pool`
+
+	p := query.Select("*")
+	if s == "" {
+		_ = p.String()
+		return
+	}
+	_, _, _ = p.Build()
+
+	for i := 0; i < 2; i++ {
+		r := query.Select("*")
+		defer r.Reset()
+		_ = r.String()
+	}
+}
+
+func golden(t *testing.T) {
+	q := query.Select("*").From("users")
+	querytest.Golden(t, "users", q)
+	_ = q.String() // want `q used after Golden returned it to the pool`
+}
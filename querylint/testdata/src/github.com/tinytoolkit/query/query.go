@@ -0,0 +1,34 @@
+// Package query is a stub of the query builder for the analyzer tests, with the signatures of the real package
+package query
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Query struct{}
+
+func Select(conditions ...string) *Query              { return &Query{} }
+func With(name string, query *Query) *Query           { return &Query{} }
+func (q *Query) From(tables ...string) *Query         { return q }
+func (q *Query) Where(expr string) *Query             { return q }
+func (q *Query) Having(condition string) *Query       { return q }
+func (q *Query) Raw(query string, args ...any) *Query { return q }
+func (q *Query) Args(args ...any) *Query              { return q }
+func (q *Query) String() string                       { return "" }
+func (q *Query) Query() (string, []any)               { return "", nil }
+func (q *Query) Build() (string, []any, error)        { return "", nil, nil }
+func (q *Query) Reset()                               {}
+
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func Exec(ctx context.Context, db Executor, q *Query) (sql.Result, error)     { return nil, nil }
+func QueryRows(ctx context.Context, db Executor, q *Query) (*sql.Rows, error) { return nil, nil }
+
+type Tx struct{}
+
+func (tx *Tx) Exec(q *Query) (sql.Result, error) { return nil, nil }
+func (tx *Tx) Query(q *Query) (*sql.Rows, error) { return nil, nil }
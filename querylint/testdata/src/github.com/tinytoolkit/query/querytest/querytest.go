@@ -0,0 +1,10 @@
+// Package querytest is a stub of the query test helpers for the analyzer tests
+package querytest
+
+import (
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+func Golden(t testing.TB, name string, queries ...*query.Query) {}
@@ -0,0 +1,90 @@
+package querytest
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+var update = flag.Bool("update", false, "update the golden files of querytest.Golden")
+
+// Golden is a function that builds the queries and compares them, formatted with query.Format and followed by
+// their arguments, with the golden file testdata/<name>.golden. Running the tests with -update writes the
+// golden files instead.
+func Golden(t testing.TB, name string, queries ...*query.Query) {
+	t.Helper()
+	var sb strings.Builder
+	for i, q := range queries {
+		s, args, err := q.Build()
+		if err != nil {
+			t.Fatalf("querytest: building query %d of %s: %v", i+1, name, err)
+			return
+		}
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(query.Format(s, query.FormatOptions{}))
+		sb.WriteString("\n")
+		if len(args) > 0 {
+			literals := make([]string, len(args))
+			for j, arg := range args {
+				literals[j] = query.Literal(arg)
+			}
+			sb.WriteString("-- args: " + strings.Join(literals, ", ") + "\n")
+		}
+	}
+	GoldenString(t, name, sb.String())
+}
+
+// GoldenString is a function that compares a string with the golden file testdata/<name>.golden, or writes
+// it when running the tests with -update
+func GoldenString(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("querytest: golden file %s does not exist, run the test with -update to create it", path)
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	if string(expected) != got {
+		t.Errorf("querytest: %s differs from the golden file, run the test with -update to accept it\n%s",
+			name, diffLines(string(expected), got))
+	}
+}
+
+// diffLines returns the lines of expected and got from the first one that differs, prefixed with - and +
+func diffLines(expected, got string) string {
+	e, g := strings.Split(expected, "\n"), strings.Split(got, "\n")
+	first := 0
+	for first < len(e) && first < len(g) && e[first] == g[first] {
+		first++
+	}
+	var sb strings.Builder
+	for _, line := range e[first:] {
+		sb.WriteString("- " + line + "\n")
+	}
+	for _, line := range g[first:] {
+		sb.WriteString("+ " + line + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,28 @@
+package querytest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+func TestGolden(t *testing.T) {
+	querytest.Golden(t, "users",
+		query.Select("users.id", "users.name", "users.email", "teams.name AS team", "count(posts.id) AS posts").
+			From("users").LeftJoin("teams", "teams.id = users.team_id").
+			Where("users.active = ? AND users.name LIKE ?").Args(true, "a%").
+			GroupBy("users.id").Limit(10),
+		query.DeleteFrom("users").Where("id = ?").Args(query.Sensitive("secret")),
+	)
+}
+
+func TestGoldenMismatch(t *testing.T) {
+	r := &recorder{TB: t}
+	querytest.GoldenString(r, "users", "SELECT 1\n")
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "users differs from the golden file") ||
+		!strings.Contains(r.errors[0], "+ SELECT 1") {
+		t.Errorf("Expected a golden file mismatch, but got %q", r.errors)
+	}
+}
@@ -0,0 +1,351 @@
+// Package querytest provides a recording fake database/sql driver for testing code built with the query package
+package querytest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+// Any is an argument matching any value in Expectation.WithArgs
+var Any = anyArg{}
+
+type anyArg struct{}
+
+// Statement is a struct holding a statement run on a Mock and its arguments
+type Statement struct {
+	Query string
+	Args  []any
+}
+
+// String is a function that returns the statement with its arguments inlined
+func (s Statement) String() string {
+	return query.Interpolate(s.Query, s.Args...)
+}
+
+// Mock is a struct implementing a fake database that records the statements run on it and answers those
+// matching its expectations with canned rows, results or errors
+type Mock struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	statements   []Statement
+	unexpected   []Statement
+}
+
+// Expectation is a struct holding an expected statement and the response it gets
+type Expectation struct {
+	description string
+	match       func(query string) bool
+	args        []any
+	hasArgs     bool
+	times       int
+	calls       int
+
+	columns      []string
+	rows         [][]driver.Value
+	lastInsertID int64
+	rowsAffected int64
+	err          error
+}
+
+// New is a function that returns a *sql.DB backed by a new Mock. When the test ends, the database is closed and
+// the expectations that were not met and the statements that were not expected are reported as errors.
+func New(t testing.TB) (*sql.DB, *Mock) {
+	t.Helper()
+	m := &Mock{}
+	db := sql.OpenDB(m.Connector())
+	t.Cleanup(func() {
+		db.Close()
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return db, m
+}
+
+// Connector is a function that returns a driver.Connector opening connections to the mock, for code wrapping
+// connectors such as query.NewConnector
+func (m *Mock) Connector() driver.Connector {
+	return connector{m}
+}
+
+// Expect is a function that expects a statement identical to query
+func (m *Mock) Expect(query string) *Expectation {
+	return m.expect(fmt.Sprintf("%q", query), func(q string) bool { return q == query })
+}
+
+// ExpectRegexp is a function that expects a statement matching the regular expression
+func (m *Mock) ExpectRegexp(pattern string) *Expectation {
+	re := regexp.MustCompile(pattern)
+	return m.expect("matching "+re.String(), re.MatchString)
+}
+
+// ExpectFingerprint is a function that expects a statement with the same query.Fingerprint as query, whatever
+// its literals, IN list lengths and whitespace
+func (m *Mock) ExpectFingerprint(q string) *Expectation {
+	fingerprint := query.Fingerprint(q)
+	return m.expect("like "+fingerprint.Text, func(q string) bool { return query.Fingerprint(q).Hash == fingerprint.Hash })
+}
+
+func (m *Mock) expect(description string, match func(string) bool) *Expectation {
+	e := &Expectation{description: description, match: match, times: 1}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// WithArgs is a function that restricts the expectation to statements with the specified arguments, where Any
+// matches any value
+func (e *Expectation) WithArgs(args ...any) *Expectation {
+	e.args = make([]any, len(args))
+	for i, arg := range args {
+		e.args[i] = convert(arg)
+	}
+	e.hasArgs = true
+	return e
+}
+
+// Times is a function that sets how many statements the expectation answers, once by default; a negative
+// number answers any number of statements, none included
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// WillReturnRows is a function that answers the statement with rows of the specified columns
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]any) *Expectation {
+	e.columns = columns
+	e.rows = make([][]driver.Value, len(rows))
+	for i, row := range rows {
+		e.rows[i] = make([]driver.Value, len(row))
+		for j, value := range row {
+			e.rows[i][j] = convert(value)
+		}
+	}
+	return e
+}
+
+// WillReturnResult is a function that answers the statement with the specified result
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.lastInsertID, e.rowsAffected = lastInsertID, rowsAffected
+	return e
+}
+
+// WillReturnError is a function that answers the statement with an error
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Statements is a function that returns the statements run on the database, in order
+func (m *Mock) Statements() []Statement {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Statement(nil), m.statements...)
+}
+
+// Queries is a function that returns the statements run on the database with their arguments inlined, in order
+func (m *Mock) Queries() []string {
+	statements := m.Statements()
+	queries := make([]string, len(statements))
+	for i, s := range statements {
+		queries[i] = s.String()
+	}
+	return queries
+}
+
+// ExpectationsWereMet is a function that returns an error listing the expectations not met and the
+// statements that were not expected
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var problems []string
+	for _, e := range m.expectations {
+		if e.times >= 0 && e.calls < e.times {
+			problems = append(problems, fmt.Sprintf("expected statement %s was run %d of %d times", e.description, e.calls, e.times))
+		}
+	}
+	for _, s := range m.unexpected {
+		problems = append(problems, fmt.Sprintf("unexpected statement %s", s))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("querytest: " + strings.Join(problems, "\n\t"))
+}
+
+// respond records a statement and returns the expectation answering it
+func (m *Mock) respond(q string, args []driver.NamedValue) (*Expectation, error) {
+	s := Statement{Query: q, Args: make([]any, len(args))}
+	for i, arg := range args {
+		if arg.Name != "" {
+			s.Args[i] = sql.Named(arg.Name, arg.Value)
+		} else {
+			s.Args[i] = arg.Value
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statements = append(m.statements, s)
+	for _, e := range m.expectations {
+		if (e.times < 0 || e.calls < e.times) && e.match(q) && e.matchArgs(args) {
+			e.calls++
+			return e, e.err
+		}
+	}
+	m.unexpected = append(m.unexpected, s)
+	return nil, fmt.Errorf("querytest: unexpected statement %s", s)
+}
+
+func (e *Expectation) matchArgs(args []driver.NamedValue) bool {
+	if !e.hasArgs {
+		return true
+	}
+	if len(args) != len(e.args) {
+		return false
+	}
+	for i, arg := range args {
+		if e.args[i] != Any && !reflect.DeepEqual(e.args[i], arg.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// convert converts a value like query.Build and database/sql do before passing it to a driver
+func convert(value any) any {
+	if value == Any {
+		return value
+	}
+	converted, err := query.DefaultConverters.Convert(value)
+	if err != nil {
+		return value
+	}
+	converted, err = driver.DefaultParameterConverter.ConvertValue(converted)
+	if err != nil {
+		return value
+	}
+	return converted
+}
+
+// record records a transaction statement issued through the driver rather than as a query, which needs no expectation
+func (m *Mock) record(q string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statements = append(m.statements, Statement{Query: q})
+}
+
+type connector struct{ m *Mock }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return &conn{m: c.m}, nil }
+func (c connector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("querytest: use querytest.New to open a database")
+}
+
+type conn struct{ m *Mock }
+
+func (c *conn) Prepare(q string) (driver.Stmt, error) { return &stmt{c: c, query: q}, nil }
+func (c *conn) Close() error                          { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	c.m.record("BEGIN")
+	return tx{c}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, q string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.m.respond(q, args)
+	if err != nil {
+		return nil, err
+	}
+	return result{e.lastInsertID, e.rowsAffected}, nil
+}
+
+func (c *conn) QueryContext(_ context.Context, q string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.m.respond(q, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: e.columns, rows: e.rows}, nil
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.c.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.QueryContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return values
+}
+
+type tx struct{ c *conn }
+
+func (tx tx) Commit() error {
+	tx.c.m.record("COMMIT")
+	return nil
+}
+
+func (tx tx) Rollback() error {
+	tx.c.m.record("ROLLBACK")
+	return nil
+}
+
+type result struct{ lastInsertID, rowsAffected int64 }
+
+func (r result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
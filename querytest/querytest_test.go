@@ -0,0 +1,104 @@
+package querytest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+func TestMock(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("SELECT id, name FROM users WHERE id = ?").WithArgs(1).
+		WillReturnRows([]string{"id", "name"}, []any{1, "alice"})
+	mock.ExpectRegexp(`^INSERT INTO users`).WithArgs(querytest.Any).WillReturnResult(2, 1)
+	mock.ExpectFingerprint("DELETE FROM users WHERE id IN (?)").Times(2)
+	mock.Expect("UPDATE users SET name = ?").WillReturnError(errors.New("readonly database"))
+
+	ctx := context.Background()
+	var name string
+	rows, err := query.QueryRows(ctx, db, query.Select("id", "name").From("users").Where("id = ?").Args(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows.Close()
+	if name != "alice" {
+		t.Errorf("Expected name 'alice', but got '%s'", name)
+	}
+
+	res, err := query.Exec(ctx, db, query.InsertInto("users").Columns("name").Values("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := res.LastInsertId(); id != 2 {
+		t.Errorf("Expected last insert id 2, but got %d", id)
+	}
+
+	for _, ids := range [][]any{{1, 2}, {3, 4, 5}} {
+		if _, err := query.Exec(ctx, db, query.DeleteFrom("users").Where("").In("id", ids...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE users SET name = ?", "carol"); err == nil || err.Error() != "readonly database" {
+		t.Errorf("Expected the canned error, but got %v", err)
+	}
+
+	expected := []string{
+		"SELECT id, name FROM users WHERE id = 1",
+		"INSERT INTO users (name) VALUES ('bob')",
+		"DELETE FROM users WHERE id IN (1, 2)",
+		"DELETE FROM users WHERE id IN (3, 4, 5)",
+		"UPDATE users SET name = 'carol'",
+	}
+	if queries := mock.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Expected queries %q, but got %q", expected, queries)
+	}
+}
+
+// recorder is a testing.TB collecting the errors it reports and the cleanups it registers
+type recorder struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (r *recorder) Helper()           {}
+func (r *recorder) Cleanup(f func())  { r.cleanups = append(r.cleanups, f) }
+func (r *recorder) Error(args ...any) { r.errors = append(r.errors, fmt.Sprint(args...)) }
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMockReport(t *testing.T) {
+	r := &recorder{TB: t}
+	db, mock := querytest.New(r)
+	mock.Expect("SELECT 1")
+	mock.Expect("SELECT 2").Times(-1)
+
+	if _, err := db.Exec("SELECT 3 WHERE x = ?", "y"); err == nil {
+		t.Errorf("Expected an error for an unexpected statement")
+	}
+	for _, cleanup := range r.cleanups {
+		cleanup()
+	}
+
+	if len(r.errors) != 1 {
+		t.Fatalf("Expected one reported error, but got %q", r.errors)
+	}
+	for _, s := range []string{`expected statement "SELECT 1" was run 0 of 1 times`, "unexpected statement SELECT 3 WHERE x = 'y'"} {
+		if !strings.Contains(r.errors[0], s) {
+			t.Errorf("Expected the report to contain '%s', but got '%s'", s, r.errors[0])
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// Router is a struct implementing Executor that runs read-only statements on a pool of reader connections
+// and every other statement on a writer pool, such as the single connection writing to a WAL database
+type Router struct {
+	reader    *sql.DB
+	writer    *sql.DB
+	window    time.Duration
+	lastWrite atomic.Int64
+}
+
+// NewRouter is a function that returns a router over the reader and writer pools. For the window following
+// a write, reads are routed to the writer so that they see the write; a zero window disables this.
+func NewRouter(reader, writer *sql.DB, window time.Duration) *Router {
+	return &Router{reader: reader, writer: writer, window: window}
+}
+
+// Reader is a function that returns the reader pool
+func (r *Router) Reader() *sql.DB {
+	return r.reader
+}
+
+// Writer is a function that returns the writer pool
+func (r *Router) Writer() *sql.DB {
+	return r.writer
+}
+
+// Route is a function that returns the pool a query string runs on and records it as a write when it is
+// routed to the writer for not being read-only
+func (r *Router) Route(query string) *sql.DB {
+	if !Classify(query).ReadOnly() {
+		r.lastWrite.Store(time.Now().UnixNano())
+		return r.writer
+	}
+	if r.window > 0 && time.Since(time.Unix(0, r.lastWrite.Load())) < r.window {
+		return r.writer
+	}
+	return r.reader
+}
+
+// ExecContext is a function that executes a query string on the pool it is routed to, or in the transaction
+// of ctx when it was started by the router
+func (r *Router) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if tx := r.tx(ctx); tx != nil {
+		return tx.ExecContext(ctx, query, args...)
+	}
+	return r.Route(query).ExecContext(ctx, query, args...)
+}
+
+// QueryContext is a function that runs a query string on the pool it is routed to, or in the transaction
+// of ctx when it was started by the router
+func (r *Router) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if tx := r.tx(ctx); tx != nil {
+		return tx.QueryContext(ctx, query, args...)
+	}
+	return r.Route(query).QueryContext(ctx, query, args...)
+}
+
+// InTx is a function that runs fn in a transaction of the writer like InTx. Queries run on the router with
+// the context of the transaction run in it.
+func (r *Router) InTx(ctx context.Context, mode TxMode, fn func(tx *Tx) error) error {
+	// the window starts once the transaction ends, whether it committed or not
+	defer func() { r.lastWrite.Store(time.Now().UnixNano()) }()
+	return InTx(ctx, r.writer, mode, fn)
+}
+
+// tx returns the transaction of the writer carried by ctx, if any
+func (r *Router) tx(ctx context.Context) *Tx {
+	if tx, ok := ctx.Value(txKey{}).(*Tx); ok && tx.db == r.writer {
+		return tx
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+func TestRouter(t *testing.T) {
+	reader, readerMock := querytest.New(t)
+	readerMock.Expect("SELECT * FROM users")
+	readerMock.Expect("PRAGMA table_info;")
+	writer, writerMock := querytest.New(t)
+	writerMock.Expect("DELETE FROM users WHERE id = ?").WithArgs(1)
+	writerMock.Expect("BEGIN IMMEDIATE TRANSACTION;")
+	writerMock.Expect("SELECT count(*) FROM users")
+	writerMock.Expect("COMMIT TRANSACTION;")
+	r := query.NewRouter(reader, writer, 0)
+	ctx := context.Background()
+
+	rows, err := query.QueryRows(ctx, r, query.Select("*").From("users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := query.Exec(ctx, r, query.DeleteFrom("users").Where("id = ?").Args(1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := query.Exec(ctx, r, query.PragmaSchema("", "table_info", "")); err != nil {
+		t.Fatal(err)
+	}
+	err = r.InTx(ctx, query.TxImmediate, func(tx *query.Tx) error {
+		// queries run on the router with the transaction context stay in the transaction
+		rows, err := query.QueryRows(tx.Context(), r, query.Select("count(*)").From("users"))
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"SELECT * FROM users", "PRAGMA table_info;"}
+	if statements := readerMock.Queries(); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Expected reader statements %q, but got %q", expected, statements)
+	}
+	expected = []string{"DELETE FROM users WHERE id = 1", "BEGIN IMMEDIATE TRANSACTION;", "SELECT count(*) FROM users", "COMMIT TRANSACTION;"}
+	if statements := writerMock.Queries(); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Expected writer statements %q, but got %q", expected, statements)
+	}
+}
+
+func TestRouterReadYourWrites(t *testing.T) {
+	reader, _ := querytest.New(t)
+	writer, writerMock := querytest.New(t)
+	writerMock.Expect("BEGIN IMMEDIATE TRANSACTION;")
+	writerMock.Expect("ROLLBACK TRANSACTION;")
+	r := query.NewRouter(reader, writer, 50*time.Millisecond)
+
+	if db := r.Route("SELECT 1"); db != r.Reader() {
+		t.Errorf("Expected a read to be routed to the reader")
+	}
+	if db := r.Route("INSERT INTO foo DEFAULT VALUES"); db != r.Writer() {
+		t.Errorf("Expected a write to be routed to the writer")
+	}
+	if db := r.Route("SELECT 1"); db != r.Writer() {
+		t.Errorf("Expected a read following a write to be routed to the writer")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if db := r.Route("SELECT 1"); db != r.Reader() {
+		t.Errorf("Expected a read after the window to be routed to the reader")
+	}
+
+	// the window starts when a transaction outlasting it ends, even when it fails
+	r.InTx(context.Background(), query.TxImmediate, func(tx *query.Tx) error {
+		time.Sleep(60 * time.Millisecond)
+		return errors.New("boom")
+	})
+	if db := r.Route("SELECT 1"); db != r.Writer() {
+		t.Errorf("Expected a read following a transaction to be routed to the writer")
+	}
+}
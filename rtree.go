@@ -0,0 +1,151 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RTreeTable is a struct representing an R*Tree virtual table indexing bounding boxes of 1 to 5 dimensions
+type RTreeTable struct {
+	Name string
+	// ID is the integer primary key column, id when empty
+	ID         string
+	Dimensions []RTreeDimension
+	// Auxiliary holds the columns stored along the boxes, which are not indexed
+	Auxiliary []string
+	// Int32 stores the coordinates as 32-bit integers with the rtree_i32 module rather than as floats
+	Int32 bool
+}
+
+// RTreeDimension is a struct holding the columns of the minimum and maximum coordinates of a dimension
+type RTreeDimension struct {
+	Min string
+	Max string
+}
+
+// BoundingBox is a struct holding the minimum and maximum coordinates of a box, one for each dimension
+type BoundingBox struct {
+	Min []float64
+	Max []float64
+}
+
+// Point is a function that returns the bounding box of a point
+func Point(coordinates ...float64) BoundingBox {
+	return BoundingBox{Min: coordinates, Max: coordinates}
+}
+
+func (t RTreeTable) id() string {
+	if t.ID == "" {
+		return "id"
+	}
+	return t.ID
+}
+
+// CreateRTree is a function that returns a CREATE VIRTUAL TABLE query for the specified R*Tree table
+func CreateRTree(table RTreeTable) *Query {
+	return getQuery().CreateRTree(table)
+}
+
+// CreateRTree is a function that returns a CREATE VIRTUAL TABLE query for the specified R*Tree table
+func (q *Query) CreateRTree(table RTreeTable) *Query {
+	if len(table.Dimensions) < 1 || len(table.Dimensions) > 5 {
+		q.setErr(fmt.Errorf("query: R*Tree table %s has %d dimensions, not 1 to 5", table.Name, len(table.Dimensions)))
+	}
+	q.query = append(q.query, "CREATE VIRTUAL TABLE "...)
+	q.query = append(q.query, table.Name...)
+	if table.Int32 {
+		q.query = append(q.query, " USING rtree_i32("...)
+	} else {
+		q.query = append(q.query, " USING rtree("...)
+	}
+	q.query = append(q.query, table.id()...)
+	for _, dimension := range table.Dimensions {
+		q.query = append(q.query, ", "...)
+		q.query = append(q.query, dimension.Min...)
+		q.query = append(q.query, ", "...)
+		q.query = append(q.query, dimension.Max...)
+	}
+	for _, column := range table.Auxiliary {
+		q.query = append(q.query, ", +"...)
+		q.query = append(q.query, column...)
+	}
+	q.query = append(q.query, ");"...)
+	return q
+}
+
+// InsertRTree is a function that returns an INSERT query storing the bounding box of a row in an R*Tree
+// table, with the values of its auxiliary columns
+func InsertRTree(table RTreeTable, id any, box BoundingBox, auxiliary ...any) *Query {
+	return getQuery().InsertRTree(table, id, box, auxiliary...)
+}
+
+// InsertRTree is a function that returns an INSERT query storing the bounding box of a row in an R*Tree
+// table, with the values of its auxiliary columns
+func (q *Query) InsertRTree(table RTreeTable, id any, box BoundingBox, auxiliary ...any) *Query {
+	if err := table.check(box); err != nil {
+		q.setErr(err)
+	}
+	if len(auxiliary) != len(table.Auxiliary) {
+		q.setErr(fmt.Errorf("query: R*Tree table %s has %d auxiliary columns but got %d values",
+			table.Name, len(table.Auxiliary), len(auxiliary)))
+	}
+	columns := []string{table.id()}
+	values := []any{id}
+	for i, dimension := range table.Dimensions {
+		columns = append(columns, dimension.Min, dimension.Max)
+		if i < len(box.Min) && i < len(box.Max) {
+			values = append(values, box.Min[i], box.Max[i])
+		}
+	}
+	columns = append(columns, table.Auxiliary...)
+	values = append(values, auxiliary...)
+	return q.InsertInto(table.Name).Columns(columns...).Values(values...)
+}
+
+// RTreeOverlaps is a function that returns the condition matching the boxes of an R*Tree table overlapping a box
+func RTreeOverlaps(table RTreeTable, box BoundingBox) Expr {
+	// a box overlaps another when it starts before the other ends and ends after the other starts
+	return rtreeRange(table, box, "<=", box.Max, ">=", box.Min)
+}
+
+// RTreeWithin is a function that returns the condition matching the boxes of an R*Tree table inside a box
+func RTreeWithin(table RTreeTable, box BoundingBox) Expr {
+	return rtreeRange(table, box, ">=", box.Min, "<=", box.Max)
+}
+
+// RTreeContains is a function that returns the condition matching the boxes of an R*Tree table containing a
+// box, such as a Point
+func RTreeContains(table RTreeTable, box BoundingBox) Expr {
+	return rtreeRange(table, box, "<=", box.Min, ">=", box.Max)
+}
+
+// rtreeRange compares the minimum and maximum coordinates of the boxes of an R*Tree table with bounds
+func rtreeRange(table RTreeTable, box BoundingBox, minOp string, minBounds []float64, maxOp string, maxBounds []float64) Expr {
+	if err := table.check(box); err != nil {
+		return Expr{SQL: "0", err: err}
+	}
+	conditions := make([]string, 0, 2*len(table.Dimensions))
+	args := make([]any, 0, 2*len(table.Dimensions))
+	for i, dimension := range table.Dimensions {
+		conditions = append(conditions,
+			table.Name+"."+dimension.Min+" "+minOp+" ?",
+			table.Name+"."+dimension.Max+" "+maxOp+" ?")
+		args = append(args, minBounds[i], maxBounds[i])
+	}
+	return Expr{SQL: strings.Join(conditions, " AND "), Args: args}
+}
+
+// check returns an error when a box does not have the dimensions of the table
+func (t RTreeTable) check(box BoundingBox) error {
+	if len(box.Min) != len(t.Dimensions) || len(box.Max) != len(t.Dimensions) {
+		return fmt.Errorf("query: R*Tree table %s has %d dimensions but the box has %d and %d coordinates",
+			t.Name, len(t.Dimensions), len(box.Min), len(box.Max))
+	}
+	return nil
+}
+
+// JoinRTree is a function that returns a JOIN clause of an R*Tree table on its id matching a column of the
+// main table
+func (q *Query) JoinRTree(table RTreeTable, column string) *Query {
+	return q.Join(table.Name, table.Name+"."+table.id()+" = "+column)
+}
@@ -0,0 +1,104 @@
+package query_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+var placesRTree = query.RTreeTable{
+	Name:       "places_rtree",
+	Dimensions: []query.RTreeDimension{{Min: "min_lng", Max: "max_lng"}, {Min: "min_lat", Max: "max_lat"}},
+	Auxiliary:  []string{"kind"},
+}
+
+func TestCreateRTree(t *testing.T) {
+	q := query.CreateRTree(placesRTree).String()
+	expected := "CREATE VIRTUAL TABLE places_rtree USING rtree(id, min_lng, max_lng, min_lat, max_lat, +kind);"
+	if q != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, q)
+	}
+
+	q = query.CreateRTree(query.RTreeTable{Name: "spans", ID: "span_id", Dimensions: []query.RTreeDimension{{Min: "start", Max: "end"}}, Int32: true}).String()
+	expected = "CREATE VIRTUAL TABLE spans USING rtree_i32(span_id, start, end);"
+	if q != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, q)
+	}
+
+	if _, _, err := query.CreateRTree(query.RTreeTable{Name: "empty"}).Build(); err == nil {
+		t.Error("Expected an error for a table without dimensions")
+	}
+}
+
+func TestInsertRTree(t *testing.T) {
+	box := query.BoundingBox{Min: []float64{2.2, 48.8}, Max: []float64{2.4, 48.9}}
+	s, args, err := query.InsertRTree(placesRTree, 7, box, "park").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "INSERT INTO places_rtree (id, min_lng, max_lng, min_lat, max_lat, kind) VALUES (?, ?, ?, ?, ?, ?)"
+	if s != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, s)
+	}
+	if expectedArgs := []any{7, 2.2, 2.4, 48.8, 48.9, "park"}; !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected args %v, but got %v", expectedArgs, args)
+	}
+}
+
+func TestInsertRTreeMismatch(t *testing.T) {
+	if _, _, err := query.InsertRTree(placesRTree, 1, query.Point(1), "park").Build(); err == nil {
+		t.Error("Expected an error for a box with missing dimensions")
+	}
+	if _, _, err := query.InsertRTree(placesRTree, 1, query.Point(1, 2)).Build(); err == nil {
+		t.Error("Expected an error for missing auxiliary values")
+	}
+}
+
+func TestRTreeRange(t *testing.T) {
+	box := query.BoundingBox{Min: []float64{0, 10}, Max: []float64{1, 11}}
+	tests := []struct {
+		condition query.Expr
+		expected  string
+		args      []any
+	}{
+		{
+			query.RTreeOverlaps(placesRTree, box),
+			"places_rtree.min_lng <= ? AND places_rtree.max_lng >= ? AND places_rtree.min_lat <= ? AND places_rtree.max_lat >= ?",
+			[]any{1.0, 0.0, 11.0, 10.0},
+		},
+		{
+			query.RTreeWithin(placesRTree, box),
+			"places_rtree.min_lng >= ? AND places_rtree.max_lng <= ? AND places_rtree.min_lat >= ? AND places_rtree.max_lat <= ?",
+			[]any{0.0, 1.0, 10.0, 11.0},
+		},
+		{
+			query.RTreeContains(placesRTree, query.Point(0.5, 10.5)),
+			"places_rtree.min_lng <= ? AND places_rtree.max_lng >= ? AND places_rtree.min_lat <= ? AND places_rtree.max_lat >= ?",
+			[]any{0.5, 0.5, 10.5, 10.5},
+		},
+	}
+
+	for _, test := range tests {
+		if test.condition.SQL != test.expected || !reflect.DeepEqual(test.condition.Args, test.args) {
+			t.Errorf("Expected condition '%s' %v, but got '%s' %v", test.expected, test.args, test.condition.SQL, test.condition.Args)
+		}
+	}
+}
+
+func TestRTreeJoin(t *testing.T) {
+	q := query.Select("places.*").From("places").JoinRTree(placesRTree, "places.id").
+		WhereExpr(query.RTreeOverlaps(placesRTree, query.Point(2.3, 48.85)))
+	expected := "SELECT places.* FROM places JOIN places_rtree ON places_rtree.id = places.id WHERE " +
+		"places_rtree.min_lng <= ? AND places_rtree.max_lng >= ? AND places_rtree.min_lat <= ? AND places_rtree.max_lat >= ?"
+	s, args, err := q.Build()
+	if err != nil || s != expected || len(args) != 4 {
+		t.Errorf("Expected query '%s', but got '%s' %v %v", expected, s, args, err)
+	}
+
+	_, _, err = query.Select("*").From("places").JoinRTree(placesRTree, "places.id").
+		WhereExpr(query.RTreeWithin(placesRTree, query.Point(1))).Build()
+	if err == nil {
+		t.Error("Expected the error of the condition to be returned by Build")
+	}
+}
@@ -0,0 +1,468 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// Schema is a struct representing the tables, views and triggers of a database
+type Schema struct {
+	Tables   []Table
+	Views    []View
+	Triggers []Trigger
+}
+
+// Table is a struct representing a table as reported by PRAGMA table_list and table_xinfo
+type Table struct {
+	Schema       string
+	Name         string
+	Type         string
+	WithoutRowID bool
+	Strict       bool
+	Columns      []Column
+	PrimaryKey   []string
+	// Unique holds the columns of the UNIQUE table constraints of more than one column
+	Unique      [][]string
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+	SQL         string
+}
+
+// Index is a struct representing an index as reported by PRAGMA index_list and index_xinfo
+type Index struct {
+	Name    string
+	Table   string
+	Unique  bool
+	Origin  string
+	Partial bool
+	Columns []string
+	SQL     string
+}
+
+// IndexColumn is a struct representing a column of an index as reported by PRAGMA index_xinfo
+type IndexColumn struct {
+	Seq     int
+	CID     int
+	Name    string
+	Desc    bool
+	Collate string
+	Key     bool
+}
+
+// ForeignKey is a struct representing a foreign key as reported by PRAGMA foreign_key_list
+type ForeignKey struct {
+	ID       int
+	Table    string
+	From     []string
+	To       []string
+	OnUpdate string
+	OnDelete string
+	Match    string
+}
+
+// View is a struct representing a view, either declared with Select or stored in sqlite_schema as SQL
+type View struct {
+	Name   string
+	Select string
+	SQL    string
+}
+
+// Trigger is a struct representing a trigger, either declared with When, Event and Actions or stored in sqlite_schema as SQL
+type Trigger struct {
+	Name    string
+	Table   string
+	When    string
+	Event   string
+	Actions string
+	SQL     string
+}
+
+// SchemaObject is a struct representing a row of sqlite_schema
+type SchemaObject struct {
+	Type      string
+	Name      string
+	TableName string
+	SQL       string
+}
+
+// Introspect is a function that reads the schema of the specified database, "main" when empty
+func Introspect(ctx context.Context, db Executor, schema string) (*Schema, error) {
+	objects, err := SchemaObjects(ctx, db, schema)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := TableList(ctx, db, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{}
+	for _, table := range tables {
+		if table.Type != "table" && table.Type != "virtual" {
+			continue
+		}
+		if err := introspectTable(ctx, db, &table, objects); err != nil {
+			return nil, err
+		}
+		s.Tables = append(s.Tables, table)
+	}
+	for _, object := range objects {
+		switch object.Type {
+		case "view":
+			s.Views = append(s.Views, View{Name: object.Name, SQL: object.SQL})
+		case "trigger":
+			s.Triggers = append(s.Triggers, Trigger{Name: object.Name, Table: object.TableName, SQL: object.SQL})
+		}
+	}
+	return s, nil
+}
+
+// IntrospectTable is a function that reads the columns, indexes and foreign keys of the specified table
+func IntrospectTable(ctx context.Context, db Executor, schema, name string) (*Table, error) {
+	objects, err := SchemaObjects(ctx, db, schema)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := TableList(ctx, db, schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		if !strings.EqualFold(table.Name, name) {
+			continue
+		}
+		if err := introspectTable(ctx, db, &table, objects); err != nil {
+			return nil, err
+		}
+		return &table, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func introspectTable(ctx context.Context, db Executor, table *Table, objects []SchemaObject) error {
+	for _, object := range objects {
+		if object.Type == "table" && strings.EqualFold(object.Name, table.Name) {
+			table.SQL = object.SQL
+		}
+	}
+
+	columns, keys, err := tableXInfo(ctx, db, table.Schema, table.Name)
+	if err != nil {
+		return err
+	}
+	table.Columns = columns
+	table.PrimaryKey = keys
+	if len(table.PrimaryKey) > 1 {
+		for i := range table.Columns {
+			table.Columns[i].PrimaryKey = false
+		}
+	} else if len(table.PrimaryKey) == 1 && strings.Contains(strings.ToUpper(table.SQL), "AUTOINCREMENT") {
+		for i := range table.Columns {
+			if table.Columns[i].PrimaryKey {
+				table.Columns[i].AutoIncrement = true
+			}
+		}
+	}
+
+	indexes, err := IndexList(ctx, db, table.Schema, table.Name)
+	if err != nil {
+		return err
+	}
+	table.Indexes = nil
+	for _, index := range indexes {
+		for _, object := range objects {
+			if object.Type == "index" && strings.EqualFold(object.Name, index.Name) {
+				index.SQL = object.SQL
+			}
+		}
+		if index.Origin != "c" && len(index.Columns) == 1 {
+			name, collate, _ := strings.Cut(strings.TrimSuffix(index.Columns[0], " DESC"), " COLLATE ")
+			for i := range table.Columns {
+				if !strings.EqualFold(table.Columns[i].Name, name) {
+					continue
+				}
+				table.Columns[i].Unique = table.Columns[i].Unique || index.Origin == "u"
+				table.Columns[i].Collate = collate
+			}
+		}
+		switch {
+		case index.Origin == "c":
+			table.Indexes = append(table.Indexes, index)
+		case index.Origin == "u" && len(index.Columns) > 1:
+			table.Unique = append(table.Unique, index.Columns)
+		}
+	}
+
+	foreignKeys, err := ForeignKeyList(ctx, db, table.Schema, table.Name)
+	if err != nil {
+		return err
+	}
+	table.ForeignKeys = foreignKeys
+	for _, fk := range foreignKeys {
+		if len(fk.From) != 1 {
+			continue
+		}
+		for i := range table.Columns {
+			column := &table.Columns[i]
+			if !strings.EqualFold(column.Name, fk.From[0]) {
+				continue
+			}
+			column.References = fk.Table
+			if fk.To[0] != "" {
+				column.References += "(" + fk.To[0] + ")"
+			}
+			if fk.OnUpdate != "NO ACTION" {
+				column.OnUpdate = fk.OnUpdate
+			}
+			if fk.OnDelete != "NO ACTION" {
+				column.OnDelete = fk.OnDelete
+			}
+		}
+	}
+	return nil
+}
+
+// SchemaObjects is a function that reads the rows of sqlite_schema for the specified database
+func SchemaObjects(ctx context.Context, db Executor, schema string) ([]SchemaObject, error) {
+	table := "sqlite_schema"
+	if schema != "" {
+		table = ident(schema) + "." + table
+	}
+	rows, err := QueryRows(ctx, db, Select("type", "name", "tbl_name", "sql").From(table).OrderBy("rowid"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []SchemaObject
+	for rows.Next() {
+		var (
+			object SchemaObject
+			sqlStr sql.NullString
+		)
+		if err := rows.Scan(&object.Type, &object.Name, &object.TableName, &sqlStr); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(object.Name, "sqlite_") {
+			continue
+		}
+		object.SQL = sqlStr.String
+		objects = append(objects, object)
+	}
+	return objects, rows.Err()
+}
+
+// TableList is a function that runs PRAGMA table_list for the specified database, skipping internal tables
+func TableList(ctx context.Context, db Executor, schema string) ([]Table, error) {
+	if schema == "" {
+		schema = "main"
+	}
+	rows, err := QueryRows(ctx, db, PragmaCall("", "table_list", ""))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []Table
+	for rows.Next() {
+		var (
+			table      Table
+			ncol       int
+			wr, strict int
+		)
+		if err := rows.Scan(&table.Schema, &table.Name, &table.Type, &ncol, &wr, &strict); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(table.Name, "sqlite_") || !strings.EqualFold(table.Schema, schema) {
+			continue
+		}
+		table.WithoutRowID = wr != 0
+		table.Strict = strict != 0
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// TableXInfo is a function that runs PRAGMA table_xinfo for the specified table, skipping hidden columns
+func TableXInfo(ctx context.Context, db Executor, schema, table string) ([]Column, error) {
+	columns, _, err := tableXInfo(ctx, db, schema, table)
+	return columns, err
+}
+
+// tableXInfo also returns the primary key columns in key order, which table_xinfo
+// reports in its pk column rather than in column order
+func tableXInfo(ctx context.Context, db Executor, schema, table string) ([]Column, []string, error) {
+	rows, err := QueryRows(ctx, db, PragmaCall(schema, "table_xinfo", quoteLiteral(table)))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var (
+		columns []Column
+		keys    []string
+	)
+	for rows.Next() {
+		var (
+			column                   Column
+			cid, notNull, pk, hidden int
+			dflt                     sql.NullString
+		)
+		if err := rows.Scan(&cid, &column.Name, &column.Type, &notNull, &dflt, &pk, &hidden); err != nil {
+			return nil, nil, err
+		}
+		if hidden == 1 {
+			continue
+		}
+		column.NotNull = notNull != 0
+		column.PrimaryKey = pk > 0
+		column.Default = dflt.String
+		columns = append(columns, column)
+		for pk > len(keys) {
+			keys = append(keys, "")
+		}
+		if pk > 0 {
+			keys[pk-1] = column.Name
+		}
+	}
+	return columns, keys, rows.Err()
+}
+
+// IndexList is a function that runs PRAGMA index_list and index_xinfo for the specified table
+func IndexList(ctx context.Context, db Executor, schema, table string) ([]Index, error) {
+	rows, err := QueryRows(ctx, db, PragmaCall(schema, "index_list", quoteLiteral(table)))
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []Index
+	for rows.Next() {
+		var (
+			index           Index
+			seq             int
+			unique, partial int
+		)
+		if err := rows.Scan(&seq, &index.Name, &unique, &index.Origin, &partial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index.Table = table
+		index.Unique = unique != 0
+		index.Partial = partial != 0
+		indexes = append(indexes, index)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range indexes {
+		columns, err := IndexXInfo(ctx, db, schema, indexes[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range columns {
+			if !column.Key {
+				continue
+			}
+			name := column.Name
+			if column.Collate != "" && !strings.EqualFold(column.Collate, "BINARY") {
+				name += " COLLATE " + column.Collate
+			}
+			if column.Desc {
+				name += " DESC"
+			}
+			indexes[i].Columns = append(indexes[i].Columns, name)
+		}
+	}
+	return indexes, nil
+}
+
+// IndexXInfo is a function that runs PRAGMA index_xinfo for the specified index
+func IndexXInfo(ctx context.Context, db Executor, schema, index string) ([]IndexColumn, error) {
+	rows, err := QueryRows(ctx, db, PragmaCall(schema, "index_xinfo", quoteLiteral(index)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []IndexColumn
+	for rows.Next() {
+		var (
+			column    IndexColumn
+			name      sql.NullString
+			collate   sql.NullString
+			desc, key int
+		)
+		if err := rows.Scan(&column.Seq, &column.CID, &name, &desc, &collate, &key); err != nil {
+			return nil, err
+		}
+		column.Name = name.String
+		column.Desc = desc != 0
+		column.Collate = collate.String
+		column.Key = key != 0
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// ForeignKeyList is a function that runs PRAGMA foreign_key_list for the specified table
+func ForeignKeyList(ctx context.Context, db Executor, schema, table string) ([]ForeignKey, error) {
+	rows, err := QueryRows(ctx, db, PragmaCall(schema, "foreign_key_list", quoteLiteral(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var (
+			id, seq                   int
+			parent, from              string
+			to                        sql.NullString
+			onUpdate, onDelete, match string
+		)
+		if err := rows.Scan(&id, &seq, &parent, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return nil, err
+		}
+		if seq == 0 {
+			foreignKeys = append(foreignKeys, ForeignKey{
+				ID:       id,
+				Table:    parent,
+				OnUpdate: onUpdate,
+				OnDelete: onDelete,
+				Match:    match,
+			})
+		}
+		fk := &foreignKeys[len(foreignKeys)-1]
+		fk.From = append(fk.From, from)
+		fk.To = append(fk.To, to.String)
+	}
+	return foreignKeys, rows.Err()
+}
+
+// ident returns name unchanged when it is a plain identifier and quoted otherwise
+func ident(name string) string {
+	for i, r := range name {
+		if r != '_' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(i > 0 && r >= '0' && r <= '9') {
+			return quoteIdent(name)
+		}
+	}
+	if name == "" {
+		return quoteIdent(name)
+	}
+	return name
+}
+
+// quoteIdent quotes an identifier with double quotes
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteLiteral quotes a string literal with single quotes
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,139 @@
+package query_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+// mockSchema answers the introspection statements of a database holding the users and members tables
+func mockSchema(mock *querytest.Mock) {
+	mock.Expect("SELECT type, name, tbl_name, sql FROM sqlite_schema ORDER BY rowid").Times(-1).WillReturnRows([]string{"type", "name", "tbl_name", "sql"},
+		[]any{"table", "users", "users", "CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT UNIQUE NOT NULL, team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE)"},
+		[]any{"table", "sqlite_sequence", "sqlite_sequence", "CREATE TABLE sqlite_sequence(name,seq)"},
+		[]any{"index", "sqlite_autoindex_users_1", "users", nil},
+		[]any{"index", "users_team", "users", "CREATE INDEX users_team ON users (team_id)"},
+		[]any{"table", "members", "members", "CREATE TABLE members (team_id INTEGER, user_id INTEGER, role TEXT, PRIMARY KEY (user_id, team_id), UNIQUE (user_id, role)) WITHOUT ROWID"},
+		[]any{"view", "emails", "emails", "CREATE VIEW emails AS SELECT email FROM users"},
+		[]any{"trigger", "users_ai", "users", "CREATE TRIGGER users_ai AFTER INSERT ON users BEGIN SELECT 1; END"},
+	)
+	mock.Expect("PRAGMA table_list;").Times(-1).WillReturnRows([]string{"schema", "name", "type", "ncol", "wr", "strict"},
+		[]any{"main", "users", "table", int64(3), int64(0), int64(0)},
+		[]any{"main", "members", "table", int64(3), int64(1), int64(0)},
+		[]any{"main", "emails", "view", int64(1), int64(0), int64(0)},
+		[]any{"main", "sqlite_schema", "table", int64(5), int64(0), int64(0)},
+		[]any{"temp", "scratch", "table", int64(1), int64(0), int64(0)},
+	)
+	xinfo := []string{"cid", "name", "type", "notnull", "dflt_value", "pk", "hidden"}
+	mock.Expect(`PRAGMA main.table_xinfo('users');`).Times(-1).WillReturnRows(xinfo,
+		[]any{int64(0), "id", "INTEGER", int64(0), nil, int64(1), int64(0)},
+		[]any{int64(1), "email", "TEXT", int64(1), nil, int64(0), int64(0)},
+		[]any{int64(2), "team_id", "INTEGER", int64(0), nil, int64(0), int64(0)},
+	)
+	mock.Expect(`PRAGMA main.table_xinfo('members');`).Times(-1).WillReturnRows(xinfo,
+		[]any{int64(0), "team_id", "INTEGER", int64(1), nil, int64(2), int64(0)},
+		[]any{int64(1), "user_id", "INTEGER", int64(1), nil, int64(1), int64(0)},
+		[]any{int64(2), "role", "TEXT", int64(0), nil, int64(0), int64(0)},
+	)
+	list := []string{"seq", "name", "unique", "origin", "partial"}
+	mock.Expect(`PRAGMA main.index_list('users');`).Times(-1).WillReturnRows(list,
+		[]any{int64(0), "users_team", int64(0), "c", int64(0)},
+		[]any{int64(1), "sqlite_autoindex_users_1", int64(1), "u", int64(0)},
+	)
+	xinfo = []string{"seqno", "cid", "name", "desc", "coll", "key"}
+	mock.Expect(`PRAGMA main.index_xinfo('users_team');`).Times(-1).WillReturnRows(xinfo,
+		[]any{int64(0), int64(2), "team_id", int64(1), "BINARY", int64(1)},
+		[]any{int64(1), int64(-1), nil, int64(0), "BINARY", int64(0)},
+	)
+	mock.Expect(`PRAGMA main.index_xinfo('sqlite_autoindex_users_1');`).Times(-1).WillReturnRows(xinfo,
+		[]any{int64(0), int64(1), "email", int64(0), "NOCASE", int64(1)},
+	)
+	mock.Expect(`PRAGMA main.index_list('members');`).Times(-1).WillReturnRows(list,
+		[]any{int64(0), "sqlite_autoindex_members_2", int64(1), "u", int64(0)},
+		[]any{int64(1), "sqlite_autoindex_members_1", int64(1), "pk", int64(0)},
+	)
+	mock.Expect(`PRAGMA main.index_xinfo('sqlite_autoindex_members_2');`).Times(-1).WillReturnRows(xinfo,
+		[]any{int64(0), int64(1), "user_id", int64(0), "BINARY", int64(1)},
+		[]any{int64(1), int64(2), "role", int64(0), "BINARY", int64(1)},
+	)
+	mock.Expect(`PRAGMA main.index_xinfo('sqlite_autoindex_members_1');`).Times(-1).WillReturnRows(xinfo,
+		[]any{int64(0), int64(1), "user_id", int64(0), "BINARY", int64(1)},
+		[]any{int64(1), int64(0), "team_id", int64(0), "BINARY", int64(1)},
+	)
+	mock.Expect(`PRAGMA main.foreign_key_list('users');`).Times(-1).WillReturnRows([]string{"id", "seq", "table", "from", "to", "on_update", "on_delete", "match"},
+		[]any{int64(0), int64(0), "teams", "team_id", "id", "NO ACTION", "CASCADE", "NONE"},
+	)
+	mock.Expect(`PRAGMA main.foreign_key_list('members');`).Times(-1)
+}
+
+func TestIntrospect(t *testing.T) {
+	db, mock := querytest.New(t)
+	mockSchema(mock)
+
+	schema, err := query.Introspect(context.Background(), db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &query.Schema{
+		Tables: []query.Table{
+			{
+				Schema: "main",
+				Name:   "users",
+				Type:   "table",
+				Columns: []query.Column{
+					{Name: "id", Type: "INTEGER", PrimaryKey: true, AutoIncrement: true},
+					{Name: "email", Type: "TEXT", NotNull: true, Unique: true, Collate: "NOCASE"},
+					{Name: "team_id", Type: "INTEGER", References: "teams(id)", OnDelete: "CASCADE"},
+				},
+				PrimaryKey: []string{"id"},
+				Indexes: []query.Index{
+					{Name: "users_team", Table: "users", Origin: "c", Columns: []string{"team_id DESC"}, SQL: "CREATE INDEX users_team ON users (team_id)"},
+				},
+				ForeignKeys: []query.ForeignKey{
+					{Table: "teams", From: []string{"team_id"}, To: []string{"id"}, OnUpdate: "NO ACTION", OnDelete: "CASCADE", Match: "NONE"},
+				},
+				SQL: "CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT UNIQUE NOT NULL, team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE)",
+			},
+			{
+				Schema:       "main",
+				Name:         "members",
+				Type:         "table",
+				WithoutRowID: true,
+				Columns: []query.Column{
+					{Name: "team_id", Type: "INTEGER", NotNull: true},
+					{Name: "user_id", Type: "INTEGER", NotNull: true},
+					{Name: "role", Type: "TEXT"},
+				},
+				PrimaryKey: []string{"user_id", "team_id"},
+				Unique:     [][]string{{"user_id", "role"}},
+				SQL:        "CREATE TABLE members (team_id INTEGER, user_id INTEGER, role TEXT, PRIMARY KEY (user_id, team_id), UNIQUE (user_id, role)) WITHOUT ROWID",
+			},
+		},
+		Views:    []query.View{{Name: "emails", SQL: "CREATE VIEW emails AS SELECT email FROM users"}},
+		Triggers: []query.Trigger{{Name: "users_ai", Table: "users", SQL: "CREATE TRIGGER users_ai AFTER INSERT ON users BEGIN SELECT 1; END"}},
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("Expected schema %+v, but got %+v", expected, schema)
+	}
+}
+
+func TestIntrospectTable(t *testing.T) {
+	db, mock := querytest.New(t)
+	mockSchema(mock)
+
+	table, err := query.IntrospectTable(context.Background(), db, "", "members")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(table.PrimaryKey, []string{"user_id", "team_id"}) {
+		t.Errorf("Expected primary key [user_id team_id], but got %v", table.PrimaryKey)
+	}
+
+	if _, err := query.IntrospectTable(context.Background(), db, "", "missing"); err == nil {
+		t.Errorf("Expected an error for a missing table")
+	}
+}
@@ -0,0 +1,200 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SearchExpr is a struct holding an FTS5 full-text query string built from phrases and operators, in which
+// user input is always quoted so that it cannot inject FTS5 syntax
+type SearchExpr struct {
+	text     string
+	compound bool
+	phrase   bool
+}
+
+// String is a function that returns the FTS5 query string
+func (s SearchExpr) String() string {
+	return s.text
+}
+
+// Empty is a function that reports whether the expression has no phrase, which SQLite rejects in MATCH
+func (s SearchExpr) Empty() bool {
+	return s.text == ""
+}
+
+// Match is a function that returns the MATCH expression of an FTS5 table with the query string bound as an
+// argument, for WhereExpr
+func (s SearchExpr) Match(tableName string) Expr {
+	return Expr{SQL: tableName + " MATCH ?", Args: []any{s.text}}
+}
+
+// SearchPhrase is a function that returns an expression matching the words of text in sequence
+func SearchPhrase(text string) SearchExpr {
+	if strings.TrimSpace(text) == "" {
+		return SearchExpr{}
+	}
+	return SearchExpr{text: searchString(text), phrase: true}
+}
+
+// SearchPrefix is a function that returns an expression matching the words of text in sequence, the last
+// one as a prefix
+func SearchPrefix(text string) SearchExpr {
+	if strings.TrimSpace(text) == "" {
+		return SearchExpr{}
+	}
+	return SearchExpr{text: searchString(text) + " *", phrase: true}
+}
+
+// SearchText is a function that sanitizes free text typed by a user into an expression matching all its
+// words, where a word ending with * is a prefix. Quotes, operators and column filters match as plain words,
+// and words without a letter or a digit, which the tokenizer would discard, are dropped.
+func SearchText(text string) SearchExpr {
+	var terms []SearchExpr
+	for _, word := range strings.Fields(text) {
+		if strings.IndexFunc(word, isTokenRune) < 0 {
+			continue
+		}
+		term := SearchPhrase(word)
+		if prefix := strings.TrimRight(word, "*"); prefix != word {
+			term = SearchPrefix(prefix)
+		}
+		if !term.Empty() {
+			terms = append(terms, term)
+		}
+	}
+	return SearchAnd(terms...)
+}
+
+// isTokenRune reports whether a rune is part of the tokens of the default FTS5 tokenizer
+func isTokenRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// SearchAnd is a function that returns an expression matching rows matched by all the expressions
+func SearchAnd(exprs ...SearchExpr) SearchExpr {
+	return searchOp(" AND ", exprs)
+}
+
+// SearchOr is a function that returns an expression matching rows matched by any of the expressions
+func SearchOr(exprs ...SearchExpr) SearchExpr {
+	return searchOp(" OR ", exprs)
+}
+
+// SearchNot is a function that returns an expression matching rows matched by expr but not by excluded
+func SearchNot(expr, excluded SearchExpr) SearchExpr {
+	if excluded.Empty() {
+		return expr
+	}
+	if expr.Empty() {
+		return SearchExpr{}
+	}
+	return SearchExpr{text: expr.group() + " NOT " + excluded.group(), compound: true}
+}
+
+// SearchNear is a function that returns an expression matching rows where the phrases appear within the
+// specified number of tokens of each other, or 10 tokens when distance is zero. FTS5 only accepts phrases
+// and prefixes in NEAR groups, so the other expressions are dropped.
+func SearchNear(distance int, phrases ...SearchExpr) SearchExpr {
+	var texts []string
+	for _, phrase := range phrases {
+		if phrase.phrase {
+			texts = append(texts, phrase.text)
+		}
+	}
+	if len(texts) == 0 {
+		return SearchExpr{}
+	}
+	text := "NEAR(" + strings.Join(texts, " ")
+	if distance > 0 {
+		text += ", " + strconv.Itoa(distance)
+	}
+	return SearchExpr{text: text + ")"}
+}
+
+// SearchColumns is a function that restricts an expression to the specified columns of the table
+func SearchColumns(columns []string, expr SearchExpr) SearchExpr {
+	if expr.Empty() || len(columns) == 0 {
+		return expr
+	}
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = searchString(column)
+	}
+	return SearchExpr{text: "{" + strings.Join(quoted, " ") + "} : " + expr.group()}
+}
+
+// group returns the expression in parentheses when it is built from operators
+func (s SearchExpr) group() string {
+	if s.compound {
+		return "(" + s.text + ")"
+	}
+	return s.text
+}
+
+// searchOp joins expressions with an operator, skipping the empty ones
+func searchOp(op string, exprs []SearchExpr) SearchExpr {
+	var nonEmpty []SearchExpr
+	for _, expr := range exprs {
+		if !expr.Empty() {
+			nonEmpty = append(nonEmpty, expr)
+		}
+	}
+	switch len(nonEmpty) {
+	case 0:
+		return SearchExpr{}
+	case 1:
+		return nonEmpty[0]
+	}
+	texts := make([]string, len(nonEmpty))
+	for i, expr := range nonEmpty {
+		texts[i] = expr.group()
+	}
+	return SearchExpr{text: strings.Join(texts, op), compound: true}
+}
+
+// searchString quotes text as an FTS5 string, in which double quotes are doubled
+func searchString(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+// BM25 is a function that returns the bm25 relevance of the rows of an FTS5 table, where lower values are
+// better matches, weighting the columns with the specified weights
+func BM25(tableName string, weights ...float64) Expr {
+	var sb strings.Builder
+	sb.WriteString("bm25(" + tableName)
+	args := make([]any, len(weights))
+	for i, weight := range weights {
+		sb.WriteString(", ?")
+		args[i] = weight
+	}
+	sb.WriteString(")")
+	return Expr{SQL: sb.String(), Args: args}
+}
+
+// Highlight is a function that returns the text of a column of an FTS5 table with the matched phrases
+// enclosed in the open and close markup
+func Highlight(tableName string, column int, open, close string) Expr {
+	return Expr{
+		SQL:  "highlight(" + tableName + ", " + strconv.Itoa(column) + ", ?, ?)",
+		Args: []any{open, close},
+	}
+}
+
+// Snippet is a function that returns a fragment of up to tokens tokens of a column of an FTS5 table, or of
+// the best column when column is -1, with the matched phrases enclosed in the open and close markup and
+// ellipsis marking the cut text
+func Snippet(tableName string, column int, open, close, ellipsis string, tokens int) Expr {
+	return Expr{
+		SQL:  "snippet(" + tableName + ", " + strconv.Itoa(column) + ", ?, ?, ?, " + strconv.Itoa(tokens) + ")",
+		Args: []any{open, close, ellipsis},
+	}
+}
+
+// OrderByRank is a function that returns an ORDER BY clause sorting the rows of a full-text query from the
+// best match
+func (q *Query) OrderByRank() *Query {
+	q.query = append(q.query, " ORDER BY rank"...)
+	return q
+}
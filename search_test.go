@@ -0,0 +1,66 @@
+package query_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tinytoolkit/query"
+)
+
+func TestSearchExpr(t *testing.T) {
+	tests := []struct {
+		search   query.SearchExpr
+		expected string
+	}{
+		{query.SearchPhrase("hello world"), `"hello world"`},
+		{query.SearchPhrase(`say "hi"`), `"say ""hi"""`},
+		{query.SearchPrefix("data"), `"data" *`},
+		{query.SearchAnd(query.SearchPhrase("a"), query.SearchOr(query.SearchPhrase("b"), query.SearchPhrase("c"))), `"a" AND ("b" OR "c")`},
+		{query.SearchOr(query.SearchPhrase(""), query.SearchPhrase("only")), `"only"`},
+		{query.SearchNot(query.SearchPhrase("sqlite"), query.SearchPrefix("my")), `"sqlite" NOT "my" *`},
+		{query.SearchNear(5, query.SearchPhrase("quick"), query.SearchPhrase("fox")), `NEAR("quick" "fox", 5)`},
+		{query.SearchNear(0, query.SearchPhrase("a"), query.SearchPhrase("b")), `NEAR("a" "b")`},
+		{query.SearchColumns([]string{"title", "body"}, query.SearchAnd(query.SearchPhrase("x"), query.SearchPhrase("y"))), `{"title" "body"} : ("x" AND "y")`},
+		{query.SearchText(`  NEAR( title:secret -"drop" OR go*  `), `"NEAR(" AND "title:secret" AND "-""drop""" AND "OR" AND "go" *`},
+		{query.SearchText(" * "), ""},
+		{query.SearchText("hello - world --*"), `"hello" AND "world"`},
+		{query.SearchNear(5, query.SearchPhrase("a"), query.SearchOr(query.SearchPhrase("b"), query.SearchPhrase("c")), query.SearchPrefix("d")), `NEAR("a" "d" *, 5)`},
+		{query.SearchNear(0, query.SearchColumns([]string{"title"}, query.SearchPhrase("x"))), ""},
+	}
+
+	for _, test := range tests {
+		if s := test.search.String(); s != test.expected {
+			t.Errorf("Expected search '%s', but got '%s'", test.expected, s)
+		}
+	}
+	if !query.SearchText("").Empty() {
+		t.Error("Expected the search of no words to be empty")
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	query, args, err := query.SelectExpr(
+		query.Expr{SQL: "rowid"},
+		query.Highlight("posts_fts", 0, "<b>", "</b>"),
+		query.Snippet("posts_fts", -1, "<b>", "</b>", "…", 16),
+		query.BM25("posts_fts", 10, 1).As("score"),
+	).
+		From("posts_fts").
+		WhereExpr(query.SearchText("go sqlite").Match("posts_fts")).
+		OrderByRank().
+		Limit(20).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SELECT rowid, highlight(posts_fts, 0, ?, ?), snippet(posts_fts, -1, ?, ?, ?, 16), bm25(posts_fts, ?, ?) AS score " +
+		"FROM posts_fts WHERE posts_fts MATCH ? ORDER BY rank LIMIT ?"
+	if query != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, query)
+	}
+	expectedArgs := []any{"<b>", "</b>", "<b>", "</b>", "…", 10.0, 1.0, `"go" AND "sqlite"`, 20}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected args %v, but got %v", expectedArgs, args)
+	}
+}
@@ -0,0 +1,218 @@
+package query
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableFor is a function that derives a table definition from the fields and tags of the struct T.
+//
+// Each exported field becomes a column named by its db tag, or its name when the tag is missing, and
+// fields tagged db:"-" are skipped. The column type is taken from the type tag or mapped from the Go
+// type, using STRICT types when the STRICT option is given. The pk, autoincrement, unique and notnull
+// tags set the matching constraint when present with an empty or true value; a numeric pk value orders
+// the columns of a composite key. The default, check and references tags hold SQL. The index tag names
+// the indexes the column belongs to as a comma-separated list, where a name suffixed with ":unique"
+// declares a unique index and columns sharing a name form a composite index.
+func TableFor[T any](tableName string, options ...string) (Table, error) {
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return Table{}, fmt.Errorf("query: %v is not a struct", typ)
+	}
+
+	table := Table{Name: tableName}
+	for _, option := range options {
+		switch strings.ToUpper(strings.TrimSpace(option)) {
+		case "STRICT":
+			table.Strict = true
+		case "WITHOUT ROWID":
+			table.WithoutRowID = true
+		default:
+			return Table{}, fmt.Errorf("query: unsupported table option %q", option)
+		}
+	}
+
+	type key struct {
+		order  int
+		column string
+	}
+	var keys []key
+	indexes := map[string]*Index{}
+	var indexNames []string
+
+	for _, field := range structFields(typ) {
+		column, err := structColumn(field, table.Strict)
+		if err != nil {
+			return Table{}, err
+		}
+
+		if value, ok := field.Tag.Lookup("pk"); ok && value != "false" {
+			order, _ := strconv.Atoi(value)
+			keys = append(keys, key{order: order, column: column.Name})
+		}
+
+		if value := field.Tag.Get("index"); value != "" {
+			for _, name := range strings.Split(value, ",") {
+				name, unique := strings.CutSuffix(strings.TrimSpace(name), ":unique")
+				index, ok := indexes[name]
+				if !ok {
+					index = &Index{Name: name, Table: tableName}
+					indexes[name] = index
+					indexNames = append(indexNames, name)
+				}
+				index.Unique = index.Unique || unique
+				index.Columns = append(index.Columns, column.Name)
+			}
+		}
+		table.Columns = append(table.Columns, column)
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].order < keys[j].order })
+	for _, key := range keys {
+		table.PrimaryKey = append(table.PrimaryKey, key.column)
+	}
+	for i := range table.Columns {
+		column := &table.Columns[i]
+		column.PrimaryKey = false
+		for _, key := range table.PrimaryKey {
+			if key == column.Name {
+				column.PrimaryKey = len(table.PrimaryKey) == 1
+			}
+		}
+		if column.AutoIncrement && !column.PrimaryKey {
+			return Table{}, fmt.Errorf("query: column %s is autoincrement but not the single primary key", column.Name)
+		}
+	}
+	for _, name := range indexNames {
+		table.Indexes = append(table.Indexes, *indexes[name])
+	}
+	return table, nil
+}
+
+// CreateTableFor is a function that returns a CREATE TABLE query derived from the struct T, followed by its CREATE INDEX queries
+func CreateTableFor[T any](tableName string, options ...string) (*Query, error) {
+	table, err := TableFor[T](tableName, options...)
+	if err != nil {
+		return nil, err
+	}
+	return CreateTableFrom(table), nil
+}
+
+// structFields returns the column fields of a struct, flattening embedded structs
+func structFields(typ reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := field.Tag.Get("db")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && embedded != timeType {
+				fields = append(fields, structFields(embedded)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// structColumn builds the column of a struct field from its tags
+func structColumn(field reflect.StructField, strict bool) (Column, error) {
+	column := Column{
+		Name:       field.Tag.Get("db"),
+		Type:       field.Tag.Get("type"),
+		Default:    field.Tag.Get("default"),
+		Check:      field.Tag.Get("check"),
+		References: field.Tag.Get("references"),
+	}
+	if column.Name == "" {
+		column.Name = field.Name
+	}
+	if column.Type == "" {
+		typ, ok := columnType(field.Type, strict)
+		if !ok {
+			return Column{}, fmt.Errorf("query: field %s has unsupported type %v, set its type tag", field.Name, field.Type)
+		}
+		column.Type = typ
+	} else if strict && !strictType(column.Type) {
+		return Column{}, fmt.Errorf("query: field %s has type %s, which STRICT tables do not support", field.Name, column.Type)
+	}
+	column.AutoIncrement = boolTag(field.Tag, "autoincrement")
+	column.Unique = boolTag(field.Tag, "unique")
+	column.NotNull = boolTag(field.Tag, "notnull")
+	return column, nil
+}
+
+// boolTag reports whether a tag is present with an empty or true value
+func boolTag(tag reflect.StructTag, key string) bool {
+	value, ok := tag.Lookup(key)
+	return ok && (value == "" || value == "true")
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	bytesType   = reflect.TypeOf([]byte(nil))
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// strictType reports whether a column type is one of the types STRICT tables allow
+func strictType(typ string) bool {
+	switch strings.ToUpper(typ) {
+	case "INT", "INTEGER", "REAL", "TEXT", "BLOB", "ANY":
+		return true
+	}
+	return false
+}
+
+// columnType maps a Go type to a SQLite type with the matching affinity, or to a STRICT type
+func columnType(typ reflect.Type, strict bool) (string, bool) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ {
+	case timeType:
+		if strict {
+			return "TEXT", true
+		}
+		return "DATETIME", true
+	case bytesType:
+		return "BLOB", true
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		if strict {
+			return "INTEGER", true
+		}
+		return "BOOLEAN", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER", true
+	case reflect.Float32, reflect.Float64:
+		return "REAL", true
+	case reflect.String:
+		return "TEXT", true
+	case reflect.Struct:
+		// sql.NullString and friends hold their value in their first field
+		if reflect.PointerTo(typ).Implements(scannerType) && typ.NumField() > 0 {
+			return columnType(typ.Field(0).Type, strict)
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,84 @@
+package query_test
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tinytoolkit/query"
+)
+
+type timestamps struct {
+	CreatedAt time.Time `db:"created_at" notnull:"" default:"CURRENT_TIMESTAMP"`
+}
+
+type user struct {
+	ID     int64          `db:"id" pk:"" autoincrement:""`
+	Email  string         `db:"email" unique:"" notnull:"" check:"email LIKE '%@%'"`
+	Name   sql.NullString `db:"name" index:"users_name"`
+	TeamID *int64         `db:"team_id" references:"teams(id)" index:"users_team_name"`
+	Active bool           `db:"active" default:"1"`
+	Score  float64        `db:"score" type:"NUMERIC"`
+	Avatar []byte         `db:"avatar"`
+	Secret string         `db:"-"`
+	note   string
+	timestamps
+}
+
+type member struct {
+	TeamID int64  `db:"team_id" pk:"1"`
+	UserID int64  `db:"user_id" pk:"2" index:"members_user:unique"`
+	Role   string `db:"role" index:"members_user:unique"`
+}
+
+func TestCreateTableFor(t *testing.T) {
+	q, err := query.CreateTableFor[user]("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT UNIQUE NOT NULL CHECK (email LIKE '%@%'), name TEXT, team_id INTEGER REFERENCES teams(id), active BOOLEAN DEFAULT 1, score NUMERIC, avatar BLOB, created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP);CREATE INDEX users_name ON users (name);CREATE INDEX users_team_name ON users (team_id);"
+	if s := q.String(); s != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, s)
+	}
+
+	q, err = query.CreateTableFor[member]("members", "WITHOUT ROWID", "STRICT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "CREATE TABLE members (team_id INTEGER, user_id INTEGER, role TEXT, PRIMARY KEY (team_id, user_id)) WITHOUT ROWID, STRICT;CREATE UNIQUE INDEX members_user ON members (user_id, role);"
+	if s := q.String(); s != expected {
+		t.Errorf("Expected query '%s', but got '%s'", expected, s)
+	}
+}
+
+func TestTableFor(t *testing.T) {
+	table, err := query.TableFor[member]("members")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(table.PrimaryKey, []string{"team_id", "user_id"}) {
+		t.Errorf("Expected primary key [team_id user_id], but got %v", table.PrimaryKey)
+	}
+
+	if _, err := query.TableFor[struct{ C chan int }]("bad"); err == nil {
+		t.Errorf("Expected an error for an unsupported field type")
+	}
+	if _, err := query.TableFor[int]("bad"); err == nil {
+		t.Errorf("Expected an error for a non-struct type")
+	}
+	if _, err := query.TableFor[struct {
+		ID int `autoincrement:""`
+	}]("bad"); err == nil {
+		t.Errorf("Expected an error for autoincrement without a primary key")
+	}
+	type created struct {
+		At string `type:"DATETIME"`
+	}
+	if _, err := query.TableFor[created]("bad", "STRICT"); err == nil {
+		t.Errorf("Expected an error for a type tag STRICT tables do not support")
+	}
+	if _, err := query.TableFor[created]("created"); err != nil {
+		t.Errorf("Expected no error for a type tag of a table that is not STRICT, but got %v", err)
+	}
+}
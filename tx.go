@@ -0,0 +1,212 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TxMode is the locking mode of a transaction started by InTx
+type TxMode string
+
+// The transaction modes supported by SQLite
+const (
+	TxDeferred  TxMode = "DEFERRED"
+	TxImmediate TxMode = "IMMEDIATE"
+	TxExclusive TxMode = "EXCLUSIVE"
+)
+
+func (m TxMode) valid() bool {
+	switch m {
+	case TxDeferred, TxImmediate, TxExclusive:
+		return true
+	}
+	return false
+}
+
+// Retry is a struct holding how InTx retries a transaction failing with a busy or locked error
+type Retry struct {
+	// Attempts is the total number of attempts, retrying is disabled below 2
+	Attempts int
+	// Delay is the wait before the first retry, doubled after every attempt
+	Delay time.Duration
+	// MaxDelay caps the wait between attempts when not zero
+	MaxDelay time.Duration
+}
+
+// DefaultRetry is the retry policy used by InTx
+var DefaultRetry = Retry{Attempts: 5, Delay: 10 * time.Millisecond, MaxDelay: time.Second}
+
+// Tx is a struct representing a transaction, or a savepoint within one, run by InTx. It implements Executor.
+type Tx struct {
+	ctx        context.Context
+	db         *sql.DB
+	conn       *sql.Conn
+	savepoints *int
+}
+
+type txKey struct{}
+
+// InTx is a function that runs fn in a transaction started with the specified mode, committing when fn
+// returns nil and rolling back when it returns an error or panics. The whole transaction is retried
+// according to DefaultRetry when SQLite reports the database busy or locked. Calls made with the
+// context of a running transaction on the same database run in a savepoint of that transaction instead.
+func InTx(ctx context.Context, db *sql.DB, mode TxMode, fn func(tx *Tx) error) error {
+	return InTxRetry(ctx, db, mode, DefaultRetry, fn)
+}
+
+// InTxRetry is a function that runs fn in a transaction like InTx with the specified retry policy
+func InTxRetry(ctx context.Context, db *sql.DB, mode TxMode, retry Retry, fn func(tx *Tx) error) error {
+	if parent, ok := ctx.Value(txKey{}).(*Tx); ok && parent.db == db {
+		return parent.InTx(fn)
+	}
+
+	delay := retry.Delay
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, db, mode, fn)
+		if err == nil || !IsBusy(err) || attempt >= retry.Attempts {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		delay = retry.backoff(delay)
+	}
+}
+
+// backoff returns the wait following delay under the retry policy
+func (r Retry) backoff(delay time.Duration) time.Duration {
+	delay *= 2
+	if r.MaxDelay > 0 && delay > r.MaxDelay {
+		return r.MaxDelay
+	}
+	return delay
+}
+
+func runTx(ctx context.Context, db *sql.DB, mode TxMode, fn func(tx *Tx) error) (err error) {
+	if !mode.valid() {
+		return fmt.Errorf("query: invalid transaction mode %q", mode)
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := Exec(ctx, conn, Begin(string(mode))); err != nil {
+		return err
+	}
+	tx := &Tx{db: db, conn: conn, savepoints: new(int)}
+	tx.ctx = context.WithValue(ctx, txKey{}, tx)
+
+	done := false
+	defer func() {
+		if done {
+			return
+		}
+		// the transaction context may be canceled, the rollback must still run
+		if _, rollbackErr := Exec(context.Background(), conn, Rollback("")); rollbackErr != nil {
+			// never return a connection with an open transaction to the pool
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		if p := recover(); p != nil {
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if _, err := Exec(ctx, conn, Commit()); err != nil {
+		return err
+	}
+	done = true
+	return nil
+}
+
+// InTx is a function that runs fn in a uniquely named savepoint of the transaction, releasing it when fn
+// returns nil and rolling back to it when fn returns an error or panics
+func (tx *Tx) InTx(fn func(tx *Tx) error) error {
+	*tx.savepoints++
+	name := "sp_" + strconv.Itoa(*tx.savepoints)
+	if _, err := Exec(tx.ctx, tx, Savepoint(name)); err != nil {
+		return err
+	}
+
+	done := false
+	defer func() {
+		if done {
+			return
+		}
+		Exec(context.Background(), tx, Rollback(name))
+		Exec(context.Background(), tx, ReleaseSavepoint(name))
+		if p := recover(); p != nil {
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if _, err := Exec(tx.ctx, tx, ReleaseSavepoint(name)); err != nil {
+		return err
+	}
+	done = true
+	return nil
+}
+
+// Context is a function that returns the context of the transaction, which nests InTx calls made with it
+func (tx *Tx) Context() context.Context {
+	return tx.ctx
+}
+
+// Exec is a function that builds the query and executes it in the transaction
+func (tx *Tx) Exec(q *Query) (sql.Result, error) {
+	return Exec(tx.ctx, tx, q)
+}
+
+// Query is a function that builds the query, runs it in the transaction and returns the resulting rows
+func (tx *Tx) Query(q *Query) (*sql.Rows, error) {
+	return QueryRows(tx.ctx, tx, q)
+}
+
+// ExecContext is a function that executes a query string in the transaction
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.conn.ExecContext(ctx, query, args...)
+}
+
+// QueryContext is a function that runs a query string in the transaction and returns the resulting rows
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.conn.QueryContext(ctx, query, args...)
+}
+
+// IsBusy is a function that reports whether err is a SQLITE_BUSY or SQLITE_LOCKED error
+func IsBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	var coder interface{ Code() int }
+	if errors.As(err, &coder) {
+		switch coder.Code() & 0xff {
+		case 5, 6:
+			return true
+		}
+	}
+	message := err.Error()
+	for _, s := range []string{"database is locked", "database table is locked", "database schema is locked", "SQLITE_BUSY", "SQLITE_LOCKED"} {
+		if strings.Contains(message, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+func TestInTx(t *testing.T) {
+	db, mock := querytest.New(t)
+	// every statement is accepted and the sequence is checked below
+	mock.ExpectRegexp("").Times(-1)
+	ctx := context.Background()
+
+	err := query.InTx(ctx, db, query.TxImmediate, func(tx *query.Tx) error {
+		if _, err := tx.Exec(query.InsertInto("foo").Columns("name").Values("a")); err != nil {
+			return err
+		}
+		// a failing savepoint rolls back without aborting the transaction
+		err := query.InTx(tx.Context(), db, query.TxImmediate, func(tx *query.Tx) error {
+			return errors.New("nested")
+		})
+		if err == nil || err.Error() != "nested" {
+			t.Errorf("Expected the nested error, but got %v", err)
+		}
+		return tx.InTx(func(tx *query.Tx) error {
+			_, err := tx.Exec(query.DeleteFrom("foo"))
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"BEGIN IMMEDIATE TRANSACTION;",
+		"INSERT INTO foo (name) VALUES ('a')",
+		"SAVEPOINT sp_1;",
+		"ROLLBACK TRANSACTION TO SAVEPOINT sp_1;",
+		"RELEASE SAVEPOINT sp_1;",
+		"SAVEPOINT sp_2;",
+		"DELETE FROM foo",
+		"RELEASE SAVEPOINT sp_2;",
+		"COMMIT TRANSACTION;",
+	}
+	if statements := mock.Queries(); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Expected statements %q, but got %q", expected, statements)
+	}
+}
+
+func TestInTxMode(t *testing.T) {
+	db, mock := querytest.New(t)
+	ctx := context.Background()
+
+	err := query.InTx(ctx, db, "IMMEDIATE; DROP TABLE users", func(tx *query.Tx) error {
+		t.Error("Expected fn not to run for an invalid mode")
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected an error for an invalid transaction mode")
+	}
+	if statements := mock.Queries(); len(statements) != 0 {
+		t.Errorf("Expected no statements, but got %q", statements)
+	}
+}
+
+func TestInTxRollback(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("BEGIN DEFERRED TRANSACTION;").Times(2)
+	mock.Expect("ROLLBACK TRANSACTION;").Times(2)
+	ctx := context.Background()
+
+	err := query.InTx(ctx, db, query.TxDeferred, func(tx *query.Tx) error {
+		return errors.New("boom")
+	})
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("Expected error boom, but got %v", err)
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p != "panic" {
+				t.Errorf("Expected the panic to propagate, but got %v", p)
+			}
+		}()
+		query.InTx(ctx, db, query.TxDeferred, func(tx *query.Tx) error {
+			panic("panic")
+		})
+	}()
+
+	expected := []string{
+		"BEGIN DEFERRED TRANSACTION;",
+		"ROLLBACK TRANSACTION;",
+		"BEGIN DEFERRED TRANSACTION;",
+		"ROLLBACK TRANSACTION;",
+	}
+	if statements := mock.Queries(); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Expected statements %q, but got %q", expected, statements)
+	}
+}
+
+func TestInTxRetry(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("BEGIN EXCLUSIVE TRANSACTION;").Times(2).WillReturnError(errors.New("database is locked"))
+	mock.Expect("BEGIN EXCLUSIVE TRANSACTION;")
+	mock.Expect("COMMIT TRANSACTION;")
+
+	retry := query.Retry{Attempts: 3, Delay: time.Millisecond}
+	calls := 0
+	err := query.InTxRetry(context.Background(), db, query.TxExclusive, retry, func(tx *query.Tx) error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts := len(mock.Queries()) - 1; attempts != 3 || calls != 1 {
+		t.Errorf("Expected 3 attempts and 1 call, but got %d attempts and %d calls", attempts, calls)
+	}
+
+	mock.Expect("BEGIN EXCLUSIVE TRANSACTION;").Times(2).WillReturnError(errors.New("database is locked"))
+	retry.Attempts = 2
+	err = query.InTxRetry(context.Background(), db, query.TxExclusive, retry, func(tx *query.Tx) error {
+		return nil
+	})
+	if !query.IsBusy(err) {
+		t.Errorf("Expected a busy error, but got %v", err)
+	}
+}
+
+type codeError int
+
+func (e codeError) Error() string { return fmt.Sprintf("sqlite error %d", int(e)) }
+func (e codeError) Code() int     { return int(e) }
+
+func TestIsBusy(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{errors.New("no such table: foo"), false},
+		{errors.New("database is locked"), true},
+		{fmt.Errorf("exec: %w", codeError(5)), true},
+		{codeError(262), true},
+		{codeError(19), false},
+	}
+	for _, test := range tests {
+		if busy := query.IsBusy(test.err); busy != test.expected {
+			t.Errorf("Expected IsBusy(%v) to be %v, but got %v", test.err, test.expected, busy)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWriterClosed is the error returned by a Writer once it is closed
+var ErrWriterClosed = errors.New("query: writer closed")
+
+// ErrBatchRolledBack is the error returned by a Writer for the statements of a batch whose transaction was
+// rolled back by the failure of another statement, such as a full disk or an I/O error
+var ErrBatchRolledBack = errors.New("query: batch rolled back")
+
+// WriterOptions is a struct holding the batching options of a Writer
+type WriterOptions struct {
+	// MaxBatch is the maximum number of statements committed together, 100 when zero
+	MaxBatch int
+	// Interval is how long a batch waits for more statements before it is committed; when zero,
+	// a batch holds the statements already queued
+	Interval time.Duration
+	// QueueSize is the number of statements that can wait for the writer, 1024 when zero
+	QueueSize int
+	// Retry is how BEGIN IMMEDIATE is retried while the database is busy, DefaultRetry when zero
+	Retry Retry
+}
+
+// WriteResult is a struct holding the result of a statement run by a Writer
+type WriteResult struct {
+	LastInsertID int64
+	// RowsAffected is the number of rows changed by Exec, or the number of rows returned to Query
+	RowsAffected int64
+	Columns      []string
+	Rows         [][]any
+}
+
+// Writer is a struct that serializes write statements from many goroutines onto a single connection,
+// committing them in batches of one BEGIN IMMEDIATE transaction each
+type Writer struct {
+	conn     *sql.Conn
+	opts     WriterOptions
+	requests chan *writeRequest
+	depth    atomic.Int64
+	mu       sync.RWMutex
+	closed   bool
+	// closing interrupts the retries of BEGIN once Close is called
+	closing chan struct{}
+	done    chan struct{}
+}
+
+type writeRequest struct {
+	ctx    context.Context
+	query  string
+	args   []any
+	rows   bool
+	result WriteResult
+	err    error
+	done   chan struct{}
+}
+
+// NewWriter is a function that reserves a connection of db for writing and starts the writer
+func NewWriter(ctx context.Context, db *sql.DB, opts WriterOptions) (*Writer, error) {
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = 100
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.Retry == (Retry{}) {
+		opts.Retry = DefaultRetry
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{
+		conn:     conn,
+		opts:     opts,
+		requests: make(chan *writeRequest, opts.QueueSize),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Exec is a function that builds the query, queues it and waits until its batch is committed
+func (w *Writer) Exec(ctx context.Context, q *Query) (WriteResult, error) {
+	return w.submit(ctx, q, false)
+}
+
+// Query is a function that builds the query, queues it and waits until its batch is committed, returning
+// the rows it produced such as those of a RETURNING clause
+func (w *Writer) Query(ctx context.Context, q *Query) (WriteResult, error) {
+	return w.submit(ctx, q, true)
+}
+
+// Depth is a function that returns the number of statements queued or in a batch not yet committed
+func (w *Writer) Depth() int {
+	return int(w.depth.Load())
+}
+
+// Close is a function that stops accepting statements, commits those queued and releases the connection
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWriterClosed
+	}
+	w.closed = true
+	close(w.closing)
+	close(w.requests)
+	w.mu.Unlock()
+
+	<-w.done
+	return w.conn.Close()
+}
+
+func (w *Writer) submit(ctx context.Context, q *Query, rows bool) (WriteResult, error) {
+	query, args, err := q.Build()
+	if err != nil {
+		return WriteResult{}, err
+	}
+	req := &writeRequest{ctx: ctx, query: query, args: args, rows: rows, done: make(chan struct{})}
+
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return WriteResult{}, ErrWriterClosed
+	}
+	w.depth.Add(1)
+	select {
+	case w.requests <- req:
+	case <-ctx.Done():
+		w.depth.Add(-1)
+		w.mu.RUnlock()
+		return WriteResult{}, ctx.Err()
+	}
+	w.mu.RUnlock()
+
+	// the statement may already be part of a batch, so its outcome is awaited even when ctx is done
+	<-req.done
+	return req.result, req.err
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	for {
+		req, ok := <-w.requests
+		if !ok {
+			return
+		}
+		w.flush(w.collect(req))
+	}
+}
+
+// collect gathers the statements of a batch, waiting up to the interval for more
+func (w *Writer) collect(first *writeRequest) []*writeRequest {
+	batch := []*writeRequest{first}
+	var timeout <-chan time.Time
+	if w.opts.Interval > 0 {
+		timer := time.NewTimer(w.opts.Interval)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for len(batch) < w.opts.MaxBatch {
+		if timeout == nil {
+			select {
+			case req, ok := <-w.requests:
+				if !ok {
+					return batch
+				}
+				batch = append(batch, req)
+				continue
+			default:
+				return batch
+			}
+		}
+		select {
+		case req, ok := <-w.requests:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, req)
+		case <-timeout:
+			return batch
+		}
+	}
+	return batch
+}
+
+// flush runs a batch in a single transaction and delivers each statement its outcome
+func (w *Writer) flush(batch []*writeRequest) {
+	defer func() {
+		for _, req := range batch {
+			w.depth.Add(-1)
+			close(req.done)
+		}
+	}()
+
+	ctx := context.Background()
+	if err := w.begin(ctx); err != nil {
+		for _, req := range batch {
+			req.err = err
+		}
+		return
+	}
+
+	var rolledBack error
+	for _, req := range batch {
+		if rolledBack != nil {
+			req.err = rolledBack
+			continue
+		}
+		if err := req.ctx.Err(); err != nil {
+			req.err = err
+			continue
+		}
+		req.result, req.err = w.execute(req)
+		// errors such as SQLITE_FULL, SQLITE_IOERR or a ROLLBACK conflict clause end the transaction
+		if req.err != nil && !w.inTransaction(req.err) {
+			rolledBack = fmt.Errorf("%w: %w", ErrBatchRolledBack, req.err)
+		}
+	}
+	if rolledBack != nil {
+		// the statements run before the failure were rolled back with it
+		for _, req := range batch {
+			if req.err == nil {
+				req.result, req.err = WriteResult{}, rolledBack
+			}
+		}
+		return
+	}
+
+	if _, err := Exec(ctx, w.conn, Commit()); err != nil {
+		if w.inTransaction(err) {
+			// a COMMIT failing while the transaction is still open, such as on a busy database, must roll it back
+			Exec(ctx, w.conn, Rollback(""))
+		} else {
+			err = fmt.Errorf("%w: %w", ErrBatchRolledBack, err)
+		}
+		for _, req := range batch {
+			if req.err == nil {
+				req.result, req.err = WriteResult{}, err
+			}
+		}
+	}
+}
+
+// begin starts the batch transaction, retrying while the database is busy
+func (w *Writer) begin(ctx context.Context) error {
+	delay := w.opts.Retry.Delay
+	for attempt := 1; ; attempt++ {
+		_, err := Exec(ctx, w.conn, Begin(string(TxImmediate)))
+		if err == nil || !IsBusy(err) || attempt >= w.opts.Retry.Attempts {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-w.closing:
+			timer.Stop()
+			return errors.Join(err, ErrWriterClosed)
+		case <-timer.C:
+		}
+		delay = w.opts.Retry.backoff(delay)
+	}
+}
+
+// inTransaction reports whether the batch transaction is still open after a statement failed with err. It asks
+// drivers exposing their autocommit state, such as mattn/go-sqlite3, and otherwise assumes the transaction is
+// open unless err is one SQLite rolls the transaction back on: SQLITE_NOMEM, SQLITE_INTERRUPT, SQLITE_IOERR and
+// SQLITE_FULL. A ROLLBACK conflict clause or RAISE(ROLLBACK) is only detected from the autocommit state.
+func (w *Writer) inTransaction(err error) bool {
+	open, known := true, false
+	w.conn.Raw(func(driverConn any) error {
+		if c, ok := driverConn.(interface{ AutoCommit() bool }); ok {
+			open, known = !c.AutoCommit(), true
+		}
+		return nil
+	})
+	if known {
+		return open
+	}
+	return !rollsBack(err)
+}
+
+// rollsBack reports whether err is an error on which SQLite rolls back the transaction
+func rollsBack(err error) bool {
+	var coder interface{ Code() int }
+	if errors.As(err, &coder) {
+		switch coder.Code() & 0xff {
+		case 7, 9, 10, 13:
+			return true
+		}
+	}
+	message := err.Error()
+	for _, s := range []string{"out of memory", "interrupted", "disk I/O error", "database or disk is full"} {
+		if strings.Contains(message, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Writer) execute(req *writeRequest) (WriteResult, error) {
+	if !req.rows {
+		res, err := w.conn.ExecContext(req.ctx, req.query, req.args...)
+		if err != nil {
+			return WriteResult{}, err
+		}
+		var result WriteResult
+		result.LastInsertID, _ = res.LastInsertId()
+		result.RowsAffected, _ = res.RowsAffected()
+		return result, nil
+	}
+
+	rows, err := w.conn.QueryContext(req.ctx, req.query, req.args...)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	defer rows.Close()
+
+	var result WriteResult
+	if result.Columns, err = rows.Columns(); err != nil {
+		return WriteResult{}, err
+	}
+	for rows.Next() {
+		values := make([]any, len(result.Columns))
+		dest := make([]any, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return WriteResult{}, err
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return WriteResult{}, err
+	}
+	result.RowsAffected = int64(len(result.Rows))
+	return result, nil
+}
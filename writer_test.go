@@ -0,0 +1,177 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tinytoolkit/query"
+	"github.com/tinytoolkit/query/querytest"
+)
+
+func TestWriter(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("BEGIN IMMEDIATE TRANSACTION;")
+	mock.Expect("INSERT INTO foo (name) VALUES (?)").WillReturnResult(7, 1)
+	mock.Expect("INSERT INTO bar (name) VALUES (?)").WillReturnError(errors.New("UNIQUE constraint failed: bar.name"))
+	mock.Expect("INSERT INTO baz (name) VALUES (?) RETURNING id").WillReturnRows([]string{"id"}, []any{9})
+	mock.Expect("COMMIT TRANSACTION;")
+
+	ctx := context.Background()
+	w, err := query.NewWriter(ctx, db, query.WriterOptions{MaxBatch: 3, Interval: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg                     sync.WaitGroup
+		foo, bar, baz          query.WriteResult
+		fooErr, barErr, bazErr error
+	)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		foo, fooErr = w.Exec(ctx, query.InsertInto("foo").Columns("name").Values("a"))
+	}()
+	go func() {
+		defer wg.Done()
+		bar, barErr = w.Exec(ctx, query.InsertInto("bar").Columns("name").Values("b"))
+	}()
+	go func() {
+		defer wg.Done()
+		baz, bazErr = w.Query(ctx, query.InsertInto("baz").Columns("name").Values("c").Returning("id"))
+	}()
+	wg.Wait()
+
+	if fooErr != nil || foo.LastInsertID != 7 || foo.RowsAffected != 1 {
+		t.Errorf("Expected last insert id 7 and 1 row affected, but got %+v (%v)", foo, fooErr)
+	}
+	if barErr == nil || !reflect.DeepEqual(bar, query.WriteResult{}) {
+		t.Errorf("Expected the statement error, but got %+v (%v)", bar, barErr)
+	}
+	if bazErr != nil || !reflect.DeepEqual(baz.Rows, [][]any{{int64(9)}}) {
+		t.Errorf("Expected returned rows [[9]], but got %+v (%v)", baz, bazErr)
+	}
+	if depth := w.Depth(); depth != 0 {
+		t.Errorf("Expected an empty queue, but got depth %d", depth)
+	}
+
+	statements := mock.Queries()
+	if statements[0] != "BEGIN IMMEDIATE TRANSACTION;" || statements[len(statements)-1] != "COMMIT TRANSACTION;" {
+		t.Errorf("Expected the batch in a single transaction, but got %q", statements)
+	}
+	batch := append([]string(nil), statements[1:len(statements)-1]...)
+	sort.Strings(batch)
+	expected := []string{
+		"INSERT INTO bar (name) VALUES ('b')",
+		"INSERT INTO baz (name) VALUES ('c') RETURNING id",
+		"INSERT INTO foo (name) VALUES ('a')",
+	}
+	if !reflect.DeepEqual(batch, expected) {
+		t.Errorf("Expected statements %q, but got %q", expected, batch)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Exec(ctx, query.DeleteFrom("foo")); !errors.Is(err, query.ErrWriterClosed) {
+		t.Errorf("Expected ErrWriterClosed, but got %v", err)
+	}
+}
+
+func TestWriterRolledBack(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("BEGIN IMMEDIATE TRANSACTION;")
+	mock.Expect("INSERT INTO bar (name) VALUES (?)").WillReturnError(errors.New("database or disk is full"))
+	// the statements queued before the failing one run, the others do not
+	mock.Expect("INSERT INTO foo (name) VALUES (?)").Times(-1)
+	mock.Expect("INSERT INTO baz (name) VALUES (?)").Times(-1)
+
+	ctx := context.Background()
+	w, err := query.NewWriter(ctx, db, query.WriterOptions{MaxBatch: 3, Interval: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i, table := range []string{"foo", "bar", "baz"} {
+		wg.Add(1)
+		go func(i int, table string) {
+			defer wg.Done()
+			_, errs[i] = w.Exec(ctx, query.InsertInto(table).Columns("name").Values("a"))
+		}(i, table)
+	}
+	wg.Wait()
+
+	if errs[1] == nil || errs[1].Error() != "database or disk is full" {
+		t.Errorf("Expected the statement error, but got %v", errs[1])
+	}
+	for _, i := range []int{0, 2} {
+		if !errors.Is(errs[i], query.ErrBatchRolledBack) {
+			t.Errorf("Expected ErrBatchRolledBack, but got %v", errs[i])
+		}
+	}
+	statements := mock.Queries()
+	if last := statements[len(statements)-1]; last != "INSERT INTO bar (name) VALUES ('a')" {
+		t.Errorf("Expected the batch to stop after the rolled back transaction, but got %q", statements)
+	}
+}
+
+func TestWriterCloseInterruptsRetry(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("BEGIN IMMEDIATE TRANSACTION;").Times(-1).WillReturnError(errors.New("database is locked"))
+
+	ctx := context.Background()
+	w, err := query.NewWriter(ctx, db, query.WriterOptions{Retry: query.Retry{Attempts: 100, Delay: time.Minute}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error)
+	go func() {
+		_, err := w.Exec(ctx, query.DeleteFrom("foo"))
+		errs <- err
+	}()
+	for len(mock.Statements()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; !errors.Is(err, query.ErrWriterClosed) {
+		t.Errorf("Expected ErrWriterClosed, but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Close to interrupt the retry, but it took %v", elapsed)
+	}
+}
+
+func TestWriterCommitError(t *testing.T) {
+	db, mock := querytest.New(t)
+	mock.Expect("BEGIN IMMEDIATE TRANSACTION;")
+	mock.Expect("DELETE FROM foo")
+	mock.Expect("COMMIT TRANSACTION;").WillReturnError(errors.New("database is locked"))
+	mock.Expect("ROLLBACK TRANSACTION;")
+
+	ctx := context.Background()
+	w, err := query.NewWriter(ctx, db, query.WriterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := w.Exec(ctx, query.DeleteFrom("foo")); err == nil || err.Error() != "database is locked" {
+		t.Errorf("Expected the commit error, but got %v", err)
+	}
+	expected := []string{"BEGIN IMMEDIATE TRANSACTION;", "DELETE FROM foo", "COMMIT TRANSACTION;", "ROLLBACK TRANSACTION;"}
+	if statements := mock.Queries(); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Expected statements %q, but got %q", expected, statements)
+	}
+}